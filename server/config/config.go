@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -16,6 +17,11 @@ type Config struct {
 		BaseURL string `yaml:"baseURL"`
 		FileURL string `yaml:"fileURL"`
 		Port    int    `yaml:"port"`
+		// CursorSecret 分页游标签名密钥，未配置时每次启动随机生成
+		CursorSecret string `yaml:"cursorSecret"`
+		// TrustedProxies 可信反向代理的 IP 或网段，只有来自这些地址的请求才采信 X-Forwarded-For；
+		// 为空时不信任任何代理，客户端 IP 取连接地址
+		TrustedProxies []string `yaml:"trustedProxies"`
 	} `yaml:"server"`
 
 	Database struct {
@@ -34,7 +40,69 @@ type Config struct {
 		DataPath         string `yaml:"dataPath"`
 		UserVideoListPath string `yaml:"userVideoListPath"`
 		CommentsPath     string `yaml:"commentsPath"`
+		// IPRegionsFile IP 地址库 CSV 文件，配置后启动时导入 ip_regions 表，文件更新后自动重新导入
+		IPRegionsFile string `yaml:"ipRegionsFile"`
 	} `yaml:"paths"`
+
+	Video struct {
+		TrashRetentionDays     int `yaml:"trashRetentionDays"`
+		PurgeIntervalMinutes   int `yaml:"purgeIntervalMinutes"`
+		UploadMaxMB            int `yaml:"uploadMaxMB"`
+		MaxScheduleDays        int `yaml:"maxScheduleDays"`
+		PublishIntervalSeconds int `yaml:"publishIntervalSeconds"`
+	} `yaml:"video"`
+
+	Draft struct {
+		RetentionDays     int `yaml:"retentionDays"`
+		GCIntervalMinutes int `yaml:"gcIntervalMinutes"`
+	} `yaml:"draft"`
+
+	Recommend struct {
+		CandidateLimit int     `yaml:"candidateLimit"`
+		HalfLifeHours  float64 `yaml:"halfLifeHours"`
+		AuthorDecay    float64 `yaml:"authorDecay"`
+	} `yaml:"recommend"`
+
+	Trending struct {
+		IntervalMinutes int `yaml:"intervalMinutes"`
+		WindowHours     int `yaml:"windowHours"`
+		Size            int `yaml:"size"`
+		RetentionDays   int `yaml:"retentionDays"`
+	} `yaml:"trending"`
+
+	Similarity struct {
+		IntervalMinutes int `yaml:"intervalMinutes"`
+		WindowDays      int `yaml:"windowDays"`
+		Neighbors       int `yaml:"neighbors"`
+		MinCommonUsers  int `yaml:"minCommonUsers"`
+		MaxUserItems    int `yaml:"maxUserItems"`
+	} `yaml:"similarity"`
+
+	Seen struct {
+		Capacity          int     `yaml:"capacity"`
+		FalsePositiveRate float64 `yaml:"falsePositiveRate"`
+	} `yaml:"seen"`
+
+	Admin struct {
+		// UIDs 管理员用户ID列表，可以干预热榜等
+		UIDs []string `yaml:"uids"`
+	} `yaml:"admin"`
+
+	Experiments []ExperimentConfig `yaml:"experiments"`
+}
+
+// ExperimentConfig A/B 实验配置
+type ExperimentConfig struct {
+	Name     string                    `yaml:"name"`
+	Variants []ExperimentVariantConfig `yaml:"variants"`
+}
+
+// ExperimentVariantConfig 实验分组配置，Percent 为分到该组的流量百分比，
+// 各组之和不超过 100，剩余流量为对照组；Strategy 必填，启动时校验
+type ExperimentVariantConfig struct {
+	Name     string `yaml:"name"`
+	Percent  int    `yaml:"percent"`
+	Strategy string `yaml:"strategy"`
 }
 
 var (
@@ -49,6 +117,8 @@ var (
 	DataPath string
 	// UseDB 是否使用数据库
 	UseDB bool
+	// IPRegionsPath IP 地址库文件路径，未配置时为空
+	IPRegionsPath string
 )
 
 // Init 初始化配置
@@ -71,6 +141,16 @@ func Init() {
 		log.Fatalf("无法解析配置文件: %v", err)
 	}
 
+	// 校验实验配置，分组流量或名称有误时拒绝启动，避免曝光记录与实际策略不符
+	if err := validateExperiments(AppConfig.Experiments); err != nil {
+		log.Fatalf("实验配置无效: %v", err)
+	}
+
+	// 未配置游标密钥时每次启动随机生成，重启或多实例部署时旧游标会失效
+	if AppConfig.Server.CursorSecret == "" {
+		log.Printf("警告: 未配置 server.cursorSecret，分页游标使用随机密钥，服务重启后或请求落到其他实例时客户端翻页将返回 400，生产环境必须配置")
+	}
+
 	// 设置全局变量
 	BaseURL = AppConfig.Server.BaseURL
 	FileURL = AppConfig.Server.FileURL
@@ -80,6 +160,12 @@ func Init() {
 	DataPath = filepath.Join(rootDir, AppConfig.Paths.DataPath)
 	userVideoListPath := filepath.Join(rootDir, AppConfig.Paths.UserVideoListPath)
 	commentsPath := filepath.Join(rootDir, AppConfig.Paths.CommentsPath)
+	if AppConfig.Paths.IPRegionsFile != "" {
+		IPRegionsPath = AppConfig.Paths.IPRegionsFile
+		if !filepath.IsAbs(IPRegionsPath) {
+			IPRegionsPath = filepath.Join(rootDir, IPRegionsPath)
+		}
+	}
 	
 	// 确保数据目录存在
 	ensureDir(DataPath)
@@ -99,6 +185,38 @@ func Init() {
 		AppConfig.Database.DBName)
 }
 
+// validateExperiments 校验实验配置：实验和分组名称不能为空且不能重复，分组不能命名为 control，
+// 每个分组必须指定 strategy，流量百分比不能为负且之和不超过 100。strategy 是否存在由使用实验的模块校验
+func validateExperiments(experiments []ExperimentConfig) error {
+	names := make(map[string]bool)
+	for _, experiment := range experiments {
+		if experiment.Name == "" || names[experiment.Name] {
+			return fmt.Errorf("实验名称为空或重复: %q", experiment.Name)
+		}
+		names[experiment.Name] = true
+
+		total := 0
+		variants := make(map[string]bool)
+		for _, variant := range experiment.Variants {
+			if variant.Name == "" || variant.Name == "control" || variants[variant.Name] {
+				return fmt.Errorf("实验 %s 的分组名称为空、重复或为 control: %q", experiment.Name, variant.Name)
+			}
+			variants[variant.Name] = true
+			if variant.Strategy == "" {
+				return fmt.Errorf("实验 %s 的分组 %s 未指定 strategy", experiment.Name, variant.Name)
+			}
+			if variant.Percent < 0 {
+				return fmt.Errorf("实验 %s 的分组 %s 流量百分比不能为负", experiment.Name, variant.Name)
+			}
+			total += variant.Percent
+		}
+		if total > 100 {
+			return fmt.Errorf("实验 %s 的分组流量之和为 %d%%，超过 100%%", experiment.Name, total)
+		}
+	}
+	return nil
+}
+
 // 确保目录存在
 func ensureDir(path string) {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
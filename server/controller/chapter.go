@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetVideoChaptersParams 设置章节参数
+type SetVideoChaptersParams struct {
+	Chapters []model.ChapterParams `json:"chapters"`
+}
+
+// SetVideoChapters 作者手动设置视频章节，传空列表时恢复为从描述解析
+func SetVideoChapters(c *gin.Context) {
+	// 获取参数
+	var params SetVideoChaptersParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存章节
+	chapters, err := model.SetVideoChaptersFromDB(c.Param("id"), getCurrentUserID(c), params.Chapters)
+	if err != nil {
+		errorResponse(c, "设置章节失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: chapters,
+	})
+}
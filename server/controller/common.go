@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"klik/server/config"
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockCurrentUserID 模拟当前登录用户ID
+const mockCurrentUserID = "2739632844317827"
+
+// getCurrentUserID 获取当前登录用户ID，实际应该从认证信息中获取
+func getCurrentUserID(c *gin.Context) string {
+	return mockCurrentUserID
+}
+
+// requireAdmin 校验当前用户是管理员，不是时已写入响应并返回 false
+func requireAdmin(c *gin.Context) bool {
+	userID := getCurrentUserID(c)
+	for _, uid := range config.AppConfig.Admin.UIDs {
+		if uid == userID {
+			return true
+		}
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Code: 403,
+		Msg:  "需要管理员权限",
+		Data: nil,
+	})
+	return false
+}
+
+// 模型层错误对应的响应码，未列出的错误返回 500
+var (
+	// notFoundErrors 资源不存在或对当前用户不可见，返回 404
+	notFoundErrors = []error{
+		model.ErrVideoNotFound, model.ErrVideoNotInTrash, model.ErrVideoNotScheduled,
+		model.ErrDraftNotFound, model.ErrHashtagNotFound, model.ErrUserNotFound,
+		model.ErrSeriesNotFound, model.ErrSubtitleNotFound,
+	}
+	// forbiddenErrors 没有操作权限，返回 403
+	forbiddenErrors = []error{
+		model.ErrVideoForbidden, model.ErrSeriesForbidden,
+	}
+	// badRequestErrors 参数错误或当前状态不允许该操作，返回 400
+	badRequestErrors = []error{
+		model.ErrInvalidCursor, model.ErrInvalidPrivacy, model.ErrTooManyPinned,
+		model.ErrInvalidPinOrder, model.ErrInvalidVideoType, model.ErrInvalidPublishTime,
+		model.ErrInvalidDraft, model.ErrDraftIncomplete, model.ErrMusicNotFound,
+		model.ErrMusicConflict, model.ErrInvalidMusic, model.ErrInvalidComment,
+		model.ErrCannotBlockSelf, model.ErrInvalidSeries, model.ErrInvalidDanmaku,
+		model.ErrInvalidSubtitle, model.ErrInvalidChapters, model.ErrInvalidPhotoPost,
+		model.ErrInvalidTrending,
+	}
+)
+
+// errorResponse 将模型层返回的错误转换为响应，prefix 为操作失败的说明
+func errorResponse(c *gin.Context, prefix string, err error) {
+	code := 500
+	switch {
+	case errorIsAny(err, notFoundErrors):
+		code = 404
+	case errorIsAny(err, forbiddenErrors):
+		code = 403
+	case errorIsAny(err, badRequestErrors):
+		code = 400
+	}
+	c.JSON(http.StatusOK, model.Response{
+		Code: code,
+		Msg:  prefix + ": " + err.Error(),
+		Data: nil,
+	})
+}
+
+// errorIsAny 判断 err 是否为 targets 中的任一错误
+func errorIsAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
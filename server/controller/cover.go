@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"klik/server/media"
+	"klik/server/model"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 自定义封面上传限制，WebP 无法用标准库解码，不支持作为封面原图
+const (
+	coverMaxBytes    = 10 << 20
+	coverJPEGQuality = 85
+)
+
+// coverImageExts 封面原图允许的扩展名
+var coverImageExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
+
+// generateCoverVariants 解码上传的封面并按 model.CoverVariants 生成各尺寸 JPEG 文件，
+// 原图比某个尺寸小时不放大，尺寸相同的只保留一份
+func generateCoverVariants(c *gin.Context, subDir string) ([]model.CoverFile, []string, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, nil, fmt.Errorf("缺少上传文件 file")
+	}
+	if file.Size > coverMaxBytes {
+		return nil, nil, fmt.Errorf("文件大小超过 %d MB", coverMaxBytes>>20)
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !coverImageExts[ext] {
+		return nil, nil, fmt.Errorf("不支持的文件格式: %s", ext)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	defer src.Close()
+
+	img, err := media.DecodeImage(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []model.CoverFile
+	var paths []string
+	for _, variant := range model.CoverVariants {
+		resized := media.ResizeImage(img, variant.MaxWidth)
+		width, height := resized.Bounds().Dx(), resized.Bounds().Dy()
+		if len(files) > 0 && files[len(files)-1].Width == width {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := media.EncodeJPEG(&buf, resized, coverJPEGQuality); err != nil {
+			removeFiles(paths)
+			return nil, nil, fmt.Errorf("生成封面失败: %v", err)
+		}
+		saved, err := writeMediaFile(&buf, subDir, newMediaFileName(".jpg"))
+		if err != nil {
+			removeFiles(paths)
+			return nil, nil, err
+		}
+		paths = append(paths, saved.Path)
+		files = append(files, model.CoverFile{
+			Variant: variant.Name,
+			URL:     saved.URL,
+			Width:   width,
+			Height:  height,
+		})
+	}
+	return files, paths, nil
+}
+
+// removeFiles 删除本地文件
+func removeFiles(paths []string) {
+	for _, p := range paths {
+		os.Remove(p)
+	}
+}
+
+// UploadVideoCover 作者上传自定义封面，生成大、中、小三种尺寸
+func UploadVideoCover(c *gin.Context) {
+	// 生成各尺寸封面
+	userID := getCurrentUserID(c)
+	subDir := path.Join(model.UserMediaDir(userID), "cover")
+	files, paths, err := generateCoverVariants(c, subDir)
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传封面失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存封面
+	covers, err := model.SetVideoCoverFromDB(c.Param("id"), userID, files)
+	if err != nil {
+		removeFiles(paths)
+		errorResponse(c, "上传封面失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: covers,
+	})
+}
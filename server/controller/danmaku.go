@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"io"
+	"klik/server/model"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// danmakuHeartbeat SSE 心跳间隔，避免代理断开空闲连接
+const danmakuHeartbeat = 25 * time.Second
+
+// danmakuHub 按视频向正在观看的 SSE 连接推送新弹幕，只在当前进程内广播
+type danmakuHub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan model.Danmaku]struct{}
+}
+
+// liveDanmaku 全局弹幕推送中心
+var liveDanmaku = &danmakuHub{subscribers: make(map[string]map[chan model.Danmaku]struct{})}
+
+// subscribe 订阅视频的新弹幕
+func (h *danmakuHub) subscribe(awemeID string) chan model.Danmaku {
+	ch := make(chan model.Danmaku, 64)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[awemeID] == nil {
+		h.subscribers[awemeID] = make(map[chan model.Danmaku]struct{})
+	}
+	h.subscribers[awemeID][ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe 取消订阅
+func (h *danmakuHub) unsubscribe(awemeID string, ch chan model.Danmaku) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers[awemeID], ch)
+	if len(h.subscribers[awemeID]) == 0 {
+		delete(h.subscribers, awemeID)
+	}
+}
+
+// publish 推送弹幕给视频的所有订阅者，接收不及时的连接直接丢弃该条弹幕
+func (h *danmakuHub) publish(danmaku model.Danmaku) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[danmaku.AwemeID] {
+		select {
+		case ch <- danmaku:
+		default:
+		}
+	}
+}
+
+// DanmakuQueryParams 弹幕查询参数，from/to 为播放时间（毫秒）
+type DanmakuQueryParams struct {
+	AwemeID string `form:"id" binding:"required"`
+	From    int    `form:"from"`
+	To      int    `form:"to"`
+}
+
+// GetVideoDanmaku 按播放时间范围获取弹幕，未指定 to 时返回 from 之后最长范围内的弹幕
+func GetVideoDanmaku(c *gin.Context) {
+	// 获取参数
+	var params DanmakuQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+	if params.To == 0 {
+		params.To = params.From + model.MaxDanmakuRangeMs
+	}
+
+	// 加载弹幕
+	list, err := model.GetDanmakuFromDB(getCurrentUserID(c), params.AwemeID, params.From, params.To)
+	if err != nil {
+		errorResponse(c, "加载弹幕失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: list,
+	})
+}
+
+// CreateVideoDanmaku 发送弹幕并推送给正在观看该视频的用户
+func CreateVideoDanmaku(c *gin.Context) {
+	// 获取参数
+	var params model.DanmakuCreateParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存弹幕
+	danmaku, err := model.CreateDanmakuFromDB(getCurrentUserID(c), params)
+	if err != nil {
+		errorResponse(c, "发送弹幕失败", err)
+		return
+	}
+	liveDanmaku.publish(danmaku)
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: danmaku,
+	})
+}
+
+// StreamVideoDanmaku 通过 Server-Sent Events 推送视频的新弹幕，事件名为 danmaku，连接建立时发送 ready，心跳事件为 ping
+func StreamVideoDanmaku(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Query("id")
+	if err := model.CheckVideoVisibleFromDB(awemeID, getCurrentUserID(c)); err != nil {
+		errorResponse(c, "订阅弹幕失败", err)
+		return
+	}
+
+	ch := liveDanmaku.subscribe(awemeID)
+	defer liveDanmaku.unsubscribe(awemeID, ch)
+
+	heartbeat := time.NewTicker(danmakuHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	// 先发送一条事件让客户端立即确认连接已建立
+	c.SSEvent("ready", awemeID)
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case danmaku := <-ch:
+			c.SSEvent("danmaku", danmaku)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetDrafts 获取当前用户的草稿列表
+func GetDrafts(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载草稿
+	userID := getCurrentUserID(c)
+	drafts, next, err := model.GetDraftsFromDB(userID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载草稿失败", err)
+		return
+	}
+
+	// 获取草稿总数
+	page := cursorPage(drafts, next)
+	if total, err := model.GetDraftCountFromDB(userID); err == nil {
+		page.Total = total
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: page,
+	})
+}
+
+// GetDraft 获取草稿详情
+func GetDraft(c *gin.Context) {
+	draft, err := model.GetDraftFromDB(c.Param("id"), getCurrentUserID(c))
+	if err != nil {
+		errorResponse(c, "加载草稿失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: draft,
+	})
+}
+
+// CreateDraft 创建草稿
+func CreateDraft(c *gin.Context) {
+	// 获取参数
+	var params model.DraftSaveParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存草稿
+	draft, err := model.CreateDraftFromDB(getCurrentUserID(c), params)
+	if err != nil {
+		errorResponse(c, "保存草稿失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: draft,
+	})
+}
+
+// UpdateDraft 修改草稿
+func UpdateDraft(c *gin.Context) {
+	// 获取参数
+	draftID := c.Param("id")
+	var params model.DraftSaveParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 修改草稿
+	draft, err := model.UpdateDraftFromDB(draftID, getCurrentUserID(c), params)
+	if err != nil {
+		errorResponse(c, "修改草稿失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: draft,
+	})
+}
+
+// DeleteDraft 删除草稿
+func DeleteDraft(c *gin.Context) {
+	if err := model.DeleteDraftFromDB(c.Param("id"), getCurrentUserID(c)); err != nil {
+		errorResponse(c, "删除草稿失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// PublishDraftParams 发布草稿参数
+type PublishDraftParams struct {
+	PublishTime int64 `json:"publish_time"` // 视频草稿的定时发布时间，0 表示立即发布
+}
+
+// PublishDraft 发布草稿为视频或图文
+func PublishDraft(c *gin.Context) {
+	// 获取参数，请求体可以为空
+	draftID := c.Param("id")
+	var params PublishDraftParams
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&params); err != nil {
+			c.JSON(http.StatusOK, model.Response{
+				Code: 500,
+				Msg:  "参数错误",
+				Data: nil,
+			})
+			return
+		}
+	}
+
+	// 发布草稿
+	result, err := model.PublishDraftFromDB(draftID, getCurrentUserID(c), params.PublishTime)
+	if err != nil {
+		errorResponse(c, "发布草稿失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: result,
+	})
+}
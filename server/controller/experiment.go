@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetExperiments 管理员查看 A/B 实验各分组的流量比例、曝光用户数和曝光次数
+func GetExperiments(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	exposures, err := model.GetExperimentExposuresFromDB()
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "加载实验数据失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: exposures,
+	})
+}
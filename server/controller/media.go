@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"klik/server/config"
+	"klik/server/model"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传文件允许的扩展名
+var (
+	videoFileExts = map[string]bool{".mp4": true, ".mov": true, ".webm": true}
+	imageFileExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true}
+	audioFileExts = map[string]bool{".mp3": true, ".aac": true, ".m4a": true}
+	subtitleExts  = map[string]bool{".srt": true, ".vtt": true}
+)
+
+// uploadedMedia 已保存的上传文件
+type uploadedMedia struct {
+	URI      string `json:"uri"`       // 相对于数据目录的路径
+	URL      string `json:"url"`       // 通过文件服务访问的地址
+	Path     string `json:"-"`         // 本地文件路径
+	Size     int64  `json:"data_size"` // 文件大小（字节）
+	FileHash string `json:"file_hash"`
+}
+
+// saveUploadedMedia 保存表单中的上传文件到数据目录的 subDir 子目录
+func saveUploadedMedia(c *gin.Context, field, subDir string, exts map[string]bool, maxBytes int64) (uploadedMedia, error) {
+	file, err := c.FormFile(field)
+	if err != nil {
+		return uploadedMedia{}, fmt.Errorf("缺少上传文件 %s", field)
+	}
+	if maxBytes > 0 && file.Size > maxBytes {
+		return uploadedMedia{}, fmt.Errorf("文件大小超过 %d MB", maxBytes>>20)
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !exts[ext] {
+		return uploadedMedia{}, errors.New("不支持的文件格式: " + ext)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return uploadedMedia{}, fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	defer src.Close()
+
+	return writeMediaFile(src, subDir, newMediaFileName(ext))
+}
+
+// newMediaFileName 生成不重复的文件名
+func newMediaFileName(ext string) string {
+	return fmt.Sprintf("%d%03d%s", time.Now().UnixMilli(), rand.Intn(1000), ext)
+}
+
+// writeMediaFile 将内容写入数据目录并计算文件哈希
+func writeMediaFile(src io.Reader, subDir, name string) (uploadedMedia, error) {
+	dir := filepath.Join(config.DataPath, subDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return uploadedMedia{}, fmt.Errorf("创建目录失败: %v", err)
+	}
+
+	localPath := filepath.Join(dir, name)
+	dst, err := os.Create(localPath)
+	if err != nil {
+		return uploadedMedia{}, fmt.Errorf("保存文件失败: %v", err)
+	}
+	defer dst.Close()
+
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(dst, hash), src)
+	if err != nil {
+		os.Remove(localPath)
+		return uploadedMedia{}, fmt.Errorf("保存文件失败: %v", err)
+	}
+
+	uri := path.Join(subDir, name)
+	return uploadedMedia{
+		URI:      uri,
+		URL:      strings.TrimSuffix(config.FileURL, "/") + "/" + uri,
+		Path:     localPath,
+		Size:     size,
+		FileHash: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// UploadMedia 上传草稿使用的视频或图片，type 为 video 或 image
+func UploadMedia(c *gin.Context) {
+	// 获取参数
+	mediaType := c.DefaultPostForm("type", "image")
+	exts := imageFileExts
+	if mediaType == "video" {
+		exts = videoFileExts
+	} else if mediaType != "image" {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "参数错误: type 只能是 video 或 image",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存到当前用户的上传目录
+	subDir := path.Join(model.UserMediaDir(getCurrentUserID(c)), mediaType)
+	media, err := saveUploadedMedia(c, "file", subDir, exts, uploadMaxBytes())
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传文件失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: media,
+	})
+}
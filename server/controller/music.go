@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"klik/server/media"
+	"klik/server/model"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseMusicID 解析路径中的音乐ID
+func parseMusicID(c *gin.Context) (int64, bool) {
+	musicID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "参数错误: 音乐ID无效",
+			Data: nil,
+		})
+		return 0, false
+	}
+	return musicID, true
+}
+
+// GetMusicDetail 获取音乐详情
+func GetMusicDetail(c *gin.Context) {
+	// 获取参数
+	musicID, ok := parseMusicID(c)
+	if !ok {
+		return
+	}
+
+	// 获取音乐详情
+	music, err := model.GetMusicDetailFromDB(musicID)
+	if err != nil {
+		errorResponse(c, "加载音乐失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: music,
+	})
+}
+
+// GetMusicVideos 获取使用该音乐的视频，原声作品排在最前
+func GetMusicVideos(c *gin.Context) {
+	// 获取参数
+	musicID, ok := parseMusicID(c)
+	if !ok {
+		return
+	}
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载视频数据
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetMusicVideosFromDB(viewerID, musicID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载音乐视频失败", err)
+		return
+	}
+
+	// 获取视频总数
+	page := cursorPage(videos, next)
+	if total, err := model.GetMusicVideoCountFromDB(viewerID, musicID); err == nil {
+		page.Total = total
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: page,
+	})
+}
+
+// UploadMusic 上传音频并登记为当前用户的原声
+func UploadMusic(c *gin.Context) {
+	// 保存到当前用户的上传目录
+	userID := getCurrentUserID(c)
+	subDir := path.Join(model.UserMediaDir(userID), "audio")
+	file, err := saveUploadedMedia(c, "file", subDir, audioFileExts, uploadMaxBytes())
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传音频失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 解析音频时长
+	duration, err := media.Duration(file.Path)
+	if err != nil {
+		os.Remove(file.Path)
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传音频失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 登记原声
+	music, err := model.CreateOriginalMusicFromDB(model.MusicCreateParams{
+		OwnerUserID: userID,
+		Title:       c.PostForm("title"),
+		PlayURL:     file.URL,
+		Duration:    int(duration.Seconds() + 0.5),
+	})
+	if err != nil {
+		os.Remove(file.Path)
+		errorResponse(c, "上传音频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: music,
+	})
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindCursorParams 读取游标分页参数，参数错误时已写入响应并返回 false
+func bindCursorParams(c *gin.Context) (model.CursorParams, bool) {
+	var params model.CursorParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return params, false
+	}
+	return params, true
+}
+
+// cursorPage 构建游标分页响应，next 为空表示没有更多数据
+func cursorPage(list interface{}, next string) model.PageResponse {
+	return model.PageResponse{
+		List:       list,
+		HasMore:    next != "",
+		NextCursor: next,
+	}
+}
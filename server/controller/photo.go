@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"klik/server/media"
+	"klik/server/model"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// photoImageMaxBytes 图文作品单张图片大小上限
+const photoImageMaxBytes = 20 << 20
+
+// savePhotoImage 校验并保存图文作品的一张图片，返回图片地址和宽高
+func savePhotoImage(file *multipart.FileHeader, subDir string) (model.PhotoImageFile, string, error) {
+	if file.Size > photoImageMaxBytes {
+		return model.PhotoImageFile{}, "", fmt.Errorf("文件大小超过 %d MB", photoImageMaxBytes>>20)
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !imageFileExts[ext] {
+		return model.PhotoImageFile{}, "", fmt.Errorf("不支持的文件格式: %s", ext)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return model.PhotoImageFile{}, "", fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	defer src.Close()
+
+	width, height, err := media.ImageSize(src)
+	if err != nil {
+		return model.PhotoImageFile{}, "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return model.PhotoImageFile{}, "", fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	saved, err := writeMediaFile(src, subDir, newMediaFileName(ext))
+	if err != nil {
+		return model.PhotoImageFile{}, "", err
+	}
+	return model.PhotoImageFile{URL: saved.URL, Width: width, Height: height}, saved.Path, nil
+}
+
+// savePhotoImages 按上传顺序保存表单中的所有 images 文件，任意一张失败时删除已保存的文件
+func savePhotoImages(c *gin.Context, subDir string) ([]model.PhotoImageFile, []string, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, fmt.Errorf("缺少上传文件 images")
+	}
+	files := form.File["images"]
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("缺少上传文件 images")
+	}
+	if len(files) > model.MaxPhotoImages {
+		return nil, nil, fmt.Errorf("图片最多 %d 张", model.MaxPhotoImages)
+	}
+
+	var images []model.PhotoImageFile
+	var paths []string
+	for i, file := range files {
+		image, localPath, err := savePhotoImage(file, subDir)
+		if err != nil {
+			removeFiles(paths)
+			return nil, nil, fmt.Errorf("第 %d 张图片: %v", i+1, err)
+		}
+		images = append(images, image)
+		paths = append(paths, localPath)
+	}
+	return images, paths, nil
+}
+
+// UploadPhotoPost 上传多张图片发布图文作品，可选择已有音乐作为背景音乐
+func UploadPhotoPost(c *gin.Context) {
+	// 获取参数
+	privateStatus, ok1 := formInt(c, "private_status", model.VideoPrivacyPublic)
+	publishTime, ok2 := formInt(c, "publish_time", 0)
+	musicID, ok3 := formInt(c, "music_id", 0)
+	if !ok1 || !ok2 || !ok3 {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存图片到当前用户的上传目录
+	userID := getCurrentUserID(c)
+	subDir := path.Join(model.UserMediaDir(userID), "photo")
+	images, paths, err := savePhotoImages(c, subDir)
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传图片失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 创建图文作品
+	awemeID, err := model.CreateVideoFromDB(model.VideoCreateParams{
+		AuthorUserID:  userID,
+		Desc:          c.PostForm("desc"),
+		VideoType:     model.VideoTypePhoto,
+		PrivateStatus: int(privateStatus),
+		PublishTime:   publishTime,
+		MusicID:       musicID,
+		Images:        images,
+	})
+	if err != nil {
+		removeFiles(paths)
+		errorResponse(c, "发布图文失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: gin.H{
+			"aweme_id":     awemeID,
+			"is_scheduled": publishTime > 0,
+		},
+	})
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPrivacySettings 获取当前用户的隐私设置
+func GetPrivacySettings(c *gin.Context) {
+	settings, err := model.GetPrivacySettingsFromDB(getCurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "加载隐私设置失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: settings,
+	})
+}
+
+// UpdatePrivacySettings 修改当前用户的隐私设置
+func UpdatePrivacySettings(c *gin.Context) {
+	// 获取参数
+	var params model.PrivacySettingsParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 修改隐私设置
+	settings, err := model.UpdatePrivacySettingsFromDB(getCurrentUserID(c), params)
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "修改隐私设置失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: settings,
+	})
+}
+
+// BlockUser 拉黑用户，被拉黑的用户不能再 @ 当前用户
+func BlockUser(c *gin.Context) {
+	if err := model.BlockUserFromDB(getCurrentUserID(c), c.Param("uid")); err != nil {
+		errorResponse(c, "拉黑用户失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// UnblockUser 取消拉黑用户
+func UnblockUser(c *gin.Context) {
+	if err := model.UnblockUserFromDB(getCurrentUserID(c), c.Param("uid")); err != nil {
+		errorResponse(c, "取消拉黑失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
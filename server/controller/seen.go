@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResetSeenVideos 清空当前用户的已曝光视频，之后推荐列表和长视频列表可以重新返回这些视频，用于测试
+func ResetSeenVideos(c *gin.Context) {
+	if err := model.ResetSeenVideosFromDB(getCurrentUserID(c)); err != nil {
+		errorResponse(c, "清空已曝光视频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSeries 获取合集详情及剧集
+func GetSeries(c *gin.Context) {
+	series, err := model.GetSeriesFromDB(getCurrentUserID(c), c.Param("id"))
+	if err != nil {
+		errorResponse(c, "加载合集失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: series,
+	})
+}
+
+// CreateSeries 创建合集
+func CreateSeries(c *gin.Context) {
+	// 获取参数
+	var params model.SeriesCreateParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 创建合集
+	series, err := model.CreateSeriesFromDB(getCurrentUserID(c), params)
+	if err != nil {
+		errorResponse(c, "创建合集失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: series,
+	})
+}
+
+// UpdateSeriesEpisodesParams 调整合集剧集参数
+type UpdateSeriesEpisodesParams struct {
+	AwemeIDs []string `json:"aweme_ids" binding:"required"`
+}
+
+// UpdateSeriesEpisodes 调整合集的剧集和顺序
+func UpdateSeriesEpisodes(c *gin.Context) {
+	// 获取参数
+	var params UpdateSeriesEpisodesParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存剧集顺序
+	series, err := model.UpdateSeriesEpisodesFromDB(c.Param("id"), getCurrentUserID(c), params.AwemeIDs)
+	if err != nil {
+		errorResponse(c, "调整合集失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: series,
+	})
+}
+
+// GetNextEpisode 获取合集中的下一集，已是最后一集时返回 null
+func GetNextEpisode(c *gin.Context) {
+	video, err := model.GetNextEpisodeFromDB(getCurrentUserID(c), c.Param("id"))
+	if err != nil {
+		errorResponse(c, "加载下一集失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: video,
+	})
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"klik/server/media"
+	"klik/server/model"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// subtitleMaxBytes 字幕文件大小上限
+const subtitleMaxBytes = 2 << 20
+
+// readSubtitleFile 读取上传的字幕文件并转换为 WebVTT
+func readSubtitleFile(c *gin.Context) ([]byte, error) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("缺少上传文件 file")
+	}
+	if file.Size > subtitleMaxBytes {
+		return nil, fmt.Errorf("文件大小超过 %d MB", subtitleMaxBytes>>20)
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !subtitleExts[ext] {
+		return nil, fmt.Errorf("不支持的文件格式: %s", ext)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, subtitleMaxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("读取上传文件失败: %v", err)
+	}
+	return media.NormalizeSubtitle(data)
+}
+
+// UploadVideoSubtitle 作者上传视频字幕（SRT 或 WebVTT），同一语言的字幕会被替换
+func UploadVideoSubtitle(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+	language, err := model.NormalizeSubtitleLanguage(c.PostForm("language"))
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "参数错误: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 校验并转换字幕
+	vtt, err := readSubtitleFile(c)
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传字幕失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存到当前用户的上传目录
+	userID := getCurrentUserID(c)
+	subDir := path.Join(model.UserMediaDir(userID), "subtitle")
+	file, err := writeMediaFile(bytes.NewReader(vtt), subDir, newMediaFileName(".vtt"))
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "上传字幕失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存字幕
+	track, err := model.SaveVideoSubtitleFromDB(awemeID, userID, language, c.PostForm("label"), file.URL)
+	if err != nil {
+		os.Remove(file.Path)
+		errorResponse(c, "上传字幕失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: track,
+	})
+}
+
+// DeleteVideoSubtitle 作者删除视频某种语言的字幕
+func DeleteVideoSubtitle(c *gin.Context) {
+	err := model.DeleteVideoSubtitleFromDB(c.Param("id"), getCurrentUserID(c), c.Param("lang"))
+	if err != nil {
+		errorResponse(c, "删除字幕失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
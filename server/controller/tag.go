@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTagVideos 获取话题详情及话题下的视频
+func GetTagVideos(c *gin.Context) {
+	// 获取参数
+	name := c.Param("name")
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 获取话题详情
+	viewerID := getCurrentUserID(c)
+	hashtag, err := model.GetHashtagFromDB(viewerID, name)
+	if err != nil {
+		errorResponse(c, "加载话题失败", err)
+		return
+	}
+
+	// 获取话题下的视频
+	videos, next, err := model.GetHashtagVideosFromDB(viewerID, name, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载话题视频失败", err)
+		return
+	}
+	page := cursorPage(videos, next)
+	page.Total = hashtag.VideoCount
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: gin.H{
+			"tag":    hashtag,
+			"videos": page,
+		},
+	})
+}
@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrendingOverrideParams 管理员干预热榜参数
+type TrendingOverrideParams struct {
+	Action   string `json:"action" binding:"required"` // pin 或 remove
+	Position int    `json:"position"`                  // pin 时固定的位置，从 1 开始
+}
+
+// GetTrending 获取视频热榜和话题热榜
+func GetTrending(c *gin.Context) {
+	trending, err := model.GetTrendingFromDB(getCurrentUserID(c))
+	if err != nil {
+		errorResponse(c, "加载热榜失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: trending,
+	})
+}
+
+// SetTrendingOverride 管理员固定或移除热榜条目，:kind 为 video 或 hashtag，:id 为视频 aweme_id 或话题名
+func SetTrendingOverride(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	// 获取参数
+	var params TrendingOverrideParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	err := model.SetTrendingOverrideFromDB(c.Param("kind"), c.Param("id"), params.Action, params.Position, getCurrentUserID(c))
+	if err != nil {
+		errorResponse(c, "修改热榜失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// ClearTrendingOverride 管理员取消对热榜条目的干预
+func ClearTrendingOverride(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	if err := model.ClearTrendingOverrideFromDB(c.Param("kind"), c.Param("id")); err != nil {
+		errorResponse(c, "修改热榜失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
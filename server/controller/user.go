@@ -7,46 +7,49 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetUserCollect 获取用户收藏
+// GetUserCollect 获取用户收藏，视频和音乐分别使用 videoCursor、musicCursor 翻页
 func GetUserCollect(c *gin.Context) {
-	// 获取用户ID，实际应该从认证信息中获取
-	userID := "2739632844317827" // 模拟当前登录用户ID
-
-	// 计算分页参数
-	start := 0
-	pageSize := 50
-
-	// 从数据库获取用户收藏的视频
-	videos, err := model.GetUserCollectVideosFromDB(userID, start, pageSize)
-	if err != nil {
+	// 获取参数
+	var params struct {
+		VideoCursor string `form:"videoCursor"`
+		MusicCursor string `form:"musicCursor"`
+		PageSize    int    `form:"pageSize"`
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
 		c.JSON(http.StatusOK, model.Response{
 			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
+			Msg:  "参数错误",
 			Data: nil,
 		})
 		return
 	}
+	limit := model.CursorParams{PageSize: params.PageSize}.Limit()
 
-	// 从数据库获取用户收藏的音乐
-	music, err := model.GetUserCollectMusicFromDB(userID, start, pageSize)
+	// 获取当前登录用户ID
+	userID := getCurrentUserID(c)
+
+	// 从数据库获取用户收藏的视频
+	videos, videoNext, err := model.GetUserCollectVideosFromDB(userID, params.VideoCursor, limit)
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载音乐数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载视频数据失败", err)
 		return
 	}
 
-	// 获取收藏视频和音乐的总数
-	videoCount, err := model.GetUserCollectVideosCountFromDB(userID)
+	// 从数据库获取用户收藏的音乐
+	music, musicNext, err := model.GetUserCollectMusicFromDB(userID, params.MusicCursor, limit)
 	if err != nil {
-		videoCount = len(videos) // 如果获取总数失败，使用当前列表长度
+		errorResponse(c, "加载音乐数据失败", err)
+		return
 	}
 
-	musicCount, err := model.GetUserCollectMusicCountFromDB(userID)
-	if err != nil {
-		musicCount = len(music) // 如果获取总数失败，使用当前列表长度
+	// 获取收藏视频和音乐的总数
+	videoPage := cursorPage(videos, videoNext)
+	if total, err := model.GetUserCollectVideosCountFromDB(userID); err == nil {
+		videoPage.Total = total
+	}
+	musicPage := cursorPage(music, musicNext)
+	if total, err := model.GetUserCollectMusicCountFromDB(userID); err == nil {
+		musicPage.Total = total
 	}
 
 	// 返回数据
@@ -54,14 +57,8 @@ func GetUserCollect(c *gin.Context) {
 		Code: 200,
 		Msg:  "",
 		Data: model.CollectResponse{
-			Video: model.PageResponse{
-				Total: videoCount,
-				List:  videos,
-			},
-			Music: model.PageResponse{
-				Total: musicCount,
-				List:  music,
-			},
+			Video: videoPage,
+			Music: musicPage,
 		},
 	})
 }
@@ -70,15 +67,15 @@ func GetUserCollect(c *gin.Context) {
 func GetUserVideoList(c *gin.Context) {
 	// 获取参数
 	userID := c.Query("id")
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
 
 	// 从数据库加载用户视频列表
-	videos, err := model.GetUserVideoListFromDB(userID)
+	videos, next, err := model.GetUserVideoListFromDB(getCurrentUserID(c), userID, params.Cursor, params.Limit())
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载用户视频列表失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载用户视频列表失败", err)
 		return
 	}
 
@@ -86,14 +83,14 @@ func GetUserVideoList(c *gin.Context) {
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: videos,
+		Data: cursorPage(videos, next),
 	})
 }
 
 // GetUserPanel 获取用户面板信息
 func GetUserPanel(c *gin.Context) {
-	// 获取用户ID，实际应该从认证信息中获取
-	userID := "2739632844317827" // 模拟当前登录用户ID
+	// 获取当前登录用户ID
+	userID := getCurrentUserID(c)
 
 	// 从数据库加载用户信息
 	user, err := model.GetUserByID(userID)
@@ -124,8 +121,8 @@ func GetUserPanel(c *gin.Context) {
 
 // GetUserFriends 获取用户好友
 func GetUserFriends(c *gin.Context) {
-	// 获取用户ID，实际应该从认证信息中获取
-	userID := "2739632844317827" // 模拟当前登录用户ID
+	// 获取当前登录用户ID
+	userID := getCurrentUserID(c)
 
 	// 从数据库加载用户好友列表
 	friends, err := model.GetUserFriendsFromDB(userID)
@@ -3,32 +3,56 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"klik/server/model"
+	"log"
 	"net/http"
-	"strconv"
 )
 
-// GetRecommendedVideos 获取推荐视频
+// GetRecommendedVideos 获取个性化推荐视频，翻页时传上一页返回的 next_cursor。
+// 排序策略由观看者在 feed_ranking 实验中的分组决定，返回的视频按分组记录曝光
 func GetRecommendedVideos(c *gin.Context) {
 	// 获取参数
-	start, _ := strconv.Atoi(c.Query("start"))
-	pageSize, _ := strconv.Atoi(c.Query("pageSize"))
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
 
-	// 检查参数
-	if pageSize <= 0 {
-		pageSize = 10
+	// 从数据库加载视频数据
+	viewerID := getCurrentUserID(c)
+	assignment := model.AssignExperiment(model.ExperimentFeedRanking, viewerID)
+	videos, next, err := model.GetRecommendVideosFromDB(viewerID, assignment.Strategy, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载视频数据失败", err)
+		return
+	}
+	// 曝光记录失败不影响返回推荐结果
+	if err := model.LogExperimentImpressionsFromDB(assignment, viewerID, "recommend", videos); err != nil {
+		log.Printf("记录推荐曝光失败: %v", err)
 	}
+	if err := model.MarkVideosSeenFromDB(viewerID, videos); err != nil {
+		log.Printf("记录已曝光视频失败: %v", err)
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: cursorPage(videos, next),
+	})
+}
 
-	var videos []model.Video
-	var err error
+// GetFollowingVideos 获取关注的作者发布的视频，上次访问之后发布的视频带 is_unseen 标记
+func GetFollowingVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
 
 	// 从数据库加载视频数据
-	videos, err = model.GetRecommendVideosFromDB(start, pageSize)
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetFollowingVideosFromDB(viewerID, params.Cursor, params.Limit())
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载视频数据失败", err)
 		return
 	}
 
@@ -36,62 +60,131 @@ func GetRecommendedVideos(c *gin.Context) {
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: model.PageResponse{
-			Total: 844,
-			List:  videos,
-		},
+		Data: cursorPage(videos, next),
 	})
 }
 
-// GetLongRecommendedVideos 获取长视频推荐
-func GetLongRecommendedVideos(c *gin.Context) {
+// GetFriendsVideos 获取好友发布、赞过或评论过的视频，每个视频带好友动态和推荐理由
+func GetFriendsVideos(c *gin.Context) {
 	// 获取参数
-	var params model.PageParams
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "参数错误",
-			Data: nil,
-		})
+	params, ok := bindCursorParams(c)
+	if !ok {
 		return
 	}
 
-	// 检查参数
-	if params.PageSize <= 0 {
-		params.PageSize = 10
+	// 从数据库加载视频数据
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetFriendsVideosFromDB(viewerID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载视频数据失败", err)
+		return
 	}
 
-	// 计算分页参数
-	start := (params.PageNo - 1) * params.PageSize
-	pageSize := params.PageSize
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: cursorPage(videos, next),
+	})
+}
+
+// GetNearbyVideos 获取同城视频，观看者资料中没有所在城市时根据请求 IP 推断
+func GetNearbyVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
 
 	// 从数据库加载视频数据
-	videos, err := model.GetLongRecommendVideosFromDB(start, pageSize)
+	viewerID := getCurrentUserID(c)
+	videos, city, next, err := model.GetNearbyVideosFromDB(viewerID, c.ClientIP(), params.Cursor, params.Limit())
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载视频数据失败", err)
 		return
 	}
 
-	// 获取长视频总数
-	total, err := model.GetVideoCountFromDB("long-video")
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: gin.H{
+			"city":   city,
+			"videos": cursorPage(videos, next),
+		},
+	})
+}
+
+// GetRelatedVideos 获取与视频相似的视频（看过这个视频的人也喜欢），pageSize 控制条数
+func GetRelatedVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载相似视频
+	videos, err := model.GetRelatedVideosFromDB(c.Query("id"), getCurrentUserID(c), params.Limit())
 	if err != nil {
-		// 如果获取总数失败，使用默认值
-		total = 844
+		errorResponse(c, "加载相似视频失败", err)
+		return
 	}
 
 	// 返回数据
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: model.PageResponse{
-			PageNo: params.PageNo,
-			Total:  total,
-			List:   videos,
-		},
+		Data: videos,
+	})
+}
+
+// GetSimilarVideos 获取内容相似的视频（更多类似内容），按共同话题、音乐、作者和标签排序，新视频也可使用
+func GetSimilarVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载相似视频
+	videos, err := model.GetSimilarContentVideosFromDB(c.Query("id"), getCurrentUserID(c), params.Limit())
+	if err != nil {
+		errorResponse(c, "加载相似视频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: videos,
+	})
+}
+
+// GetLongRecommendedVideos 获取长视频推荐，返回的视频记入已曝光集合
+func GetLongRecommendedVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载视频数据
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetLongRecommendVideosFromDB(viewerID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载视频数据失败", err)
+		return
+	}
+	if err := model.MarkVideosSeenFromDB(viewerID, videos); err != nil {
+		log.Printf("记录已曝光视频失败: %v", err)
+	}
+
+	// 返回数据，列表会跳过已曝光的视频，无法准确统计总数，不返回 total
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: cursorPage(videos, next),
 	})
 }
 
@@ -101,13 +194,9 @@ func GetVideoComments(c *gin.Context) {
 	videoID := c.Query("id")
 
 	// 加载评论数据
-	comments, err := model.GetVideoCommentsFromPostgres(videoID)
+	comments, err := model.GetVideoCommentsFromPostgres(videoID, getCurrentUserID(c))
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载评论数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载评论数据失败", err)
 		return
 	}
 
@@ -119,11 +208,17 @@ func GetVideoComments(c *gin.Context) {
 	})
 }
 
-// GetPrivateVideos 获取私有视频
-func GetPrivateVideos(c *gin.Context) {
+// CreateVideoCommentParams 发表评论参数
+type CreateVideoCommentParams struct {
+	AwemeID string `json:"aweme_id" binding:"required"`
+	Text    string `json:"text" binding:"required"`
+}
+
+// CreateVideoComment 发表视频评论，评论中的 @用户 会收到通知
+func CreateVideoComment(c *gin.Context) {
 	// 获取参数
-	var params model.PageParams
-	if err := c.ShouldBindQuery(&params); err != nil {
+	var params CreateVideoCommentParams
+	if err := c.ShouldBindJSON(&params); err != nil {
 		c.JSON(http.StatusOK, model.Response{
 			Code: 500,
 			Msg:  "参数错误",
@@ -132,197 +227,152 @@ func GetPrivateVideos(c *gin.Context) {
 		return
 	}
 
-	// 检查参数
-	if params.PageSize <= 0 {
-		params.PageSize = 10
+	// 保存评论
+	comment, err := model.CreateVideoCommentFromDB(params.AwemeID, getCurrentUserID(c), params.Text)
+	if err != nil {
+		errorResponse(c, "发表评论失败", err)
+		return
 	}
 
-	// 计算分页参数
-	start := (params.PageNo - 1) * params.PageSize
-	pageSize := params.PageSize
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: comment,
+	})
+}
+
+// GetPrivateVideos 获取私有视频
+func GetPrivateVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
 
 	// 从数据库加载视频数据
-	videos, err := model.GetPrivateVideosFromDB(start, pageSize)
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetPrivateVideosFromDB(viewerID, params.Cursor, params.Limit())
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载视频数据失败", err)
 		return
 	}
 
-	// 获取私有视频总数
-	total, err := model.GetVideoCountFromDB("private-video")
-	if err != nil {
-		// 如果获取总数失败，使用默认值
-		total = 10
+	// 获取视频总数
+	page := cursorPage(videos, next)
+	if total, err := model.GetVideoCountFromDB(viewerID, "private-video"); err == nil {
+		page.Total = total
 	}
 
 	// 返回数据
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: model.PageResponse{
-			PageNo: params.PageNo,
-			Total:  total,
-			List:   videos,
-		},
+		Data: page,
 	})
 }
 
 // GetLikedVideos 获取喜欢的视频
 func GetLikedVideos(c *gin.Context) {
 	// 获取参数
-	var params model.PageParams
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "参数错误",
-			Data: nil,
-		})
+	params, ok := bindCursorParams(c)
+	if !ok {
 		return
 	}
 
-	// 检查参数
-	if params.PageSize <= 0 {
-		params.PageSize = 10
-	}
-
-	// 计算分页参数
-	start := (params.PageNo - 1) * params.PageSize
-	pageSize := params.PageSize
-
 	// 从数据库加载视频数据
-	videos, err := model.GetLikedVideosFromDB(start, pageSize)
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetLikedVideosFromDB(viewerID, params.Cursor, params.Limit())
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载视频数据失败", err)
 		return
 	}
 
-	// 获取喜欢的视频总数
-	// 注意：这里需要一个特殊的函数来获取当前用户喜欢的视频总数
-	// 暂时使用固定值或者视频列表长度
-	total := len(videos)
-	if total == 0 {
-		total = 150 // 默认值
-	}
-
 	// 返回数据
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: model.PageResponse{
-			PageNo: params.PageNo,
-			Total:  total,
-			List:   videos,
-		},
+		Data: cursorPage(videos, next),
 	})
 }
 
 // GetMyVideos 获取我的视频
 func GetMyVideos(c *gin.Context) {
 	// 获取参数
-	var params model.PageParams
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "参数错误",
-			Data: nil,
-		})
+	params, ok := bindCursorParams(c)
+	if !ok {
 		return
 	}
 
-	// 检查参数
-	if params.PageSize <= 0 {
-		params.PageSize = 10
-	}
-
-	// 计算分页参数
-	start := (params.PageNo - 1) * params.PageSize
-	pageSize := params.PageSize
-
 	// 从数据库加载视频数据
-	videos, err := model.GetMyVideosFromDB(start, pageSize)
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetMyVideosFromDB(viewerID, params.Cursor, params.Limit())
 	if err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
-			Data: nil,
-		})
+		errorResponse(c, "加载视频数据失败", err)
 		return
 	}
 
-	// 获取我的视频总数
-	// 注意：这里需要一个特殊的函数来获取当前用户的视频总数
-	// 暂时使用视频列表长度
-	total := len(videos)
-
 	// 返回数据
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: model.PageResponse{
-			PageNo: params.PageNo,
-			Total:  total,
-			List:   videos,
-		},
+		Data: cursorPage(videos, next),
 	})
 }
 
 // GetHistoryVideos 获取历史视频
 func GetHistoryVideos(c *gin.Context) {
 	// 获取参数
-	var params model.PageParams
-	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusOK, model.Response{
-			Code: 500,
-			Msg:  "参数错误",
-			Data: nil,
-		})
+	params, ok := bindCursorParams(c)
+	if !ok {
 		return
 	}
 
-	// 检查参数
-	if params.PageSize <= 0 {
-		params.PageSize = 10
+	// 从数据库加载视频数据
+	viewerID := getCurrentUserID(c)
+	videos, next, err := model.GetHistoryVideosFromDB(viewerID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载视频数据失败", err)
+		return
 	}
 
-	// 计算分页参数
-	start := (params.PageNo - 1) * params.PageSize
-	pageSize := params.PageSize
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: cursorPage(videos, next),
+	})
+}
 
-	// 从数据库加载视频数据
-	videos, err := model.GetHistoryVideosFromDB(start, pageSize)
-	if err != nil {
+// RecordVideoPlayParams 播放上报参数
+type RecordVideoPlayParams struct {
+	WatchMs int `json:"watch_ms"` // 本次观看时长（毫秒）
+}
+
+// RecordVideoPlay 上报一次播放，用于统计完播率并记录观看历史，看过的视频不再出现在推荐列表中
+func RecordVideoPlay(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+	var params RecordVideoPlayParams
+	if err := c.ShouldBindJSON(&params); err != nil {
 		c.JSON(http.StatusOK, model.Response{
 			Code: 500,
-			Msg:  "加载视频数据失败: " + err.Error(),
+			Msg:  "参数错误",
 			Data: nil,
 		})
 		return
 	}
 
-	// 获取历史视频总数
-	// 注意：这里需要一个特殊的函数来获取当前用户的历史视频总数
-	// 暂时使用视频列表长度或默认值
-	total := len(videos)
-	if total == 0 {
-		total = 150 // 默认值
+	// 记录播放
+	if err := model.RecordVideoPlayFromDB(awemeID, getCurrentUserID(c), params.WatchMs); err != nil {
+		errorResponse(c, "上报播放失败", err)
+		return
 	}
 
 	// 返回数据
 	c.JSON(http.StatusOK, model.Response{
 		Code: 200,
 		Msg:  "",
-		Data: model.PageResponse{
-			PageNo: params.PageNo,
-			Total:  total,
-			List:   videos,
-		},
+		Data: nil,
 	})
 }
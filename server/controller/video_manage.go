@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"klik/server/model"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateVideo 作者编辑视频描述、隐私等信息
+func UpdateVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+	var params model.VideoUpdateParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 更新视频
+	if err := model.UpdateVideoFromDB(awemeID, getCurrentUserID(c), params); err != nil {
+		errorResponse(c, "编辑视频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// DeleteVideo 作者删除视频，视频进入回收站
+func DeleteVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+
+	// 删除视频
+	if err := model.DeleteVideoFromDB(awemeID, getCurrentUserID(c)); err != nil {
+		errorResponse(c, "删除视频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// GetTrashVideos 获取回收站视频
+func GetTrashVideos(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载回收站视频
+	userID := getCurrentUserID(c)
+	videos, next, err := model.GetTrashVideosFromDB(userID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载回收站视频失败", err)
+		return
+	}
+
+	// 获取回收站视频总数
+	page := cursorPage(videos, next)
+	if total, err := model.GetTrashVideoCountFromDB(userID); err == nil {
+		page.Total = total
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: page,
+	})
+}
+
+// RestoreVideo 从回收站恢复视频
+func RestoreVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+
+	// 恢复视频
+	if err := model.RestoreVideoFromDB(awemeID, getCurrentUserID(c)); err != nil {
+		errorResponse(c, "恢复视频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// PinVideoOrderParams 置顶排序参数
+type PinVideoOrderParams struct {
+	AwemeIDs []string `json:"aweme_ids"`
+}
+
+// pinnedVideosResponse 返回当前用户置顶视频顺序
+func pinnedVideosResponse(c *gin.Context, userID string) {
+	awemeIDs, err := model.GetPinnedVideoIDsFromDB(userID)
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "加载置顶视频失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: gin.H{"aweme_ids": awemeIDs},
+	})
+}
+
+// PinVideo 置顶视频
+func PinVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+	userID := getCurrentUserID(c)
+
+	// 置顶视频
+	if err := model.PinVideoFromDB(awemeID, userID); err != nil {
+		errorResponse(c, "置顶视频失败", err)
+		return
+	}
+
+	// 返回当前置顶顺序
+	pinnedVideosResponse(c, userID)
+}
+
+// UnpinVideo 取消置顶视频
+func UnpinVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+	userID := getCurrentUserID(c)
+
+	// 取消置顶
+	if err := model.UnpinVideoFromDB(awemeID, userID); err != nil {
+		errorResponse(c, "取消置顶失败", err)
+		return
+	}
+
+	// 返回当前置顶顺序
+	pinnedVideosResponse(c, userID)
+}
+
+// ReorderPinnedVideos 调整置顶视频顺序
+func ReorderPinnedVideos(c *gin.Context) {
+	// 获取参数
+	var params PinVideoOrderParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+	userID := getCurrentUserID(c)
+
+	// 调整顺序
+	if err := model.ReorderPinnedVideosFromDB(userID, params.AwemeIDs); err != nil {
+		errorResponse(c, "调整置顶顺序失败", err)
+		return
+	}
+
+	// 返回当前置顶顺序
+	pinnedVideosResponse(c, userID)
+}
@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"klik/server/config"
+	"klik/server/media"
+	"klik/server/model"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadMaxBytes 上传文件大小上限
+func uploadMaxBytes() int64 {
+	maxMB := config.AppConfig.Video.UploadMaxMB
+	if maxMB <= 0 {
+		maxMB = 500
+	}
+	return int64(maxMB) << 20
+}
+
+// formInt 读取表单中的整数参数，缺省时返回默认值
+func formInt(c *gin.Context, key string, def int64) (int64, bool) {
+	value := c.PostForm(key)
+	if value == "" {
+		return def, true
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// UploadVideo 上传并发布视频，publish_time 为未来时间时定时发布
+func UploadVideo(c *gin.Context) {
+	// 获取参数
+	videoType := c.DefaultPostForm("video_type", "recommend-video")
+	duration, ok1 := formInt(c, "duration", 0)
+	privateStatus, ok2 := formInt(c, "private_status", model.VideoPrivacyPublic)
+	publishTime, ok3 := formInt(c, "publish_time", 0)
+	width, ok4 := formInt(c, "width", 0)
+	height, ok5 := formInt(c, "height", 0)
+	musicID, ok6 := formInt(c, "music_id", 0)
+	originalSound, err := strconv.ParseBool(c.DefaultPostForm("original_sound", "false"))
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 || err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 保存视频文件
+	file, err := saveUploadedMedia(c, "file", "video", videoFileExts, uploadMaxBytes())
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 400,
+			Msg:  "上传视频失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 未提供时长时从文件中解析
+	if duration == 0 {
+		if d, err := media.Duration(file.Path); err == nil {
+			duration = d.Milliseconds()
+		}
+	}
+
+	// 创建视频
+	awemeID, err := model.CreateVideoFromDB(model.VideoCreateParams{
+		AuthorUserID:  getCurrentUserID(c),
+		Desc:          c.PostForm("desc"),
+		VideoType:     videoType,
+		Duration:      int(duration),
+		PrivateStatus: int(privateStatus),
+		PublishTime:   publishTime,
+		PlayURI:       file.URI,
+		PlayURL:       file.URL,
+		Width:         int(width),
+		Height:        int(height),
+		DataSize:      file.Size,
+		FileHash:      file.FileHash,
+		MusicID:       musicID,
+		OriginalSound: originalSound,
+	})
+	if err != nil {
+		os.Remove(file.Path)
+		errorResponse(c, "发布视频失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: gin.H{
+			"aweme_id":     awemeID,
+			"is_scheduled": publishTime > 0,
+		},
+	})
+}
+
+// GetScheduledVideos 获取当前用户的定时发布视频
+func GetScheduledVideos(c *gin.Context) {
+	videos, err := model.GetScheduledVideosFromDB(getCurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "加载定时发布视频失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: videos,
+	})
+}
+
+// RescheduleVideoParams 修改定时发布时间参数
+type RescheduleVideoParams struct {
+	PublishTime int64 `json:"publish_time" binding:"required"`
+}
+
+// RescheduleVideo 修改定时发布时间
+func RescheduleVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+	var params RescheduleVideoParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusOK, model.Response{
+			Code: 500,
+			Msg:  "参数错误",
+			Data: nil,
+		})
+		return
+	}
+
+	// 修改发布时间
+	if err := model.RescheduleVideoFromDB(awemeID, getCurrentUserID(c), params.PublishTime); err != nil {
+		errorResponse(c, "修改发布时间失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// CancelScheduledVideo 取消定时发布并删除视频
+func CancelScheduledVideo(c *gin.Context) {
+	// 获取参数
+	awemeID := c.Param("id")
+
+	// 取消定时发布
+	if err := model.CancelScheduledVideoFromDB(awemeID, getCurrentUserID(c)); err != nil {
+		errorResponse(c, "取消定时发布失败", err)
+		return
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: nil,
+	})
+}
+
+// GetNotifications 获取当前用户的通知列表
+func GetNotifications(c *gin.Context) {
+	// 获取参数
+	params, ok := bindCursorParams(c)
+	if !ok {
+		return
+	}
+
+	// 从数据库加载通知
+	userID := getCurrentUserID(c)
+	notifications, next, err := model.GetNotificationsFromDB(userID, params.Cursor, params.Limit())
+	if err != nil {
+		errorResponse(c, "加载通知失败", err)
+		return
+	}
+
+	// 获取通知总数
+	page := cursorPage(notifications, next)
+	if total, err := model.GetNotificationCountFromDB(userID); err == nil {
+		page.Total = total
+	}
+
+	// 返回数据
+	c.JSON(http.StatusOK, model.Response{
+		Code: 200,
+		Msg:  "",
+		Data: page,
+	})
+}
@@ -0,0 +1,31 @@
+package job
+
+import (
+	"klik/server/config"
+	"klik/server/model"
+	"log"
+	"time"
+)
+
+// startDraftGCJob 定期删除超过保留期未修改的草稿
+func startDraftGCJob() {
+	interval := time.Duration(config.AppConfig.Draft.GCIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := model.PurgeExpiredDraftsFromDB()
+			if err != nil {
+				log.Printf("草稿清理失败: %v", err)
+			} else if purged > 0 {
+				log.Printf("草稿清理完成: 删除 %d 个草稿", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
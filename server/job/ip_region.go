@@ -0,0 +1,42 @@
+package job
+
+import (
+	"klik/server/config"
+	"klik/server/model"
+	"log"
+	"os"
+	"time"
+)
+
+// ipRegionCheckInterval IP 地址库文件更新检查间隔
+const ipRegionCheckInterval = time.Hour
+
+// startIPRegionImportJob 启动时导入 IP 地址库，之后文件修改时间变化时重新导入，未配置文件时不启动
+func startIPRegionImportJob() {
+	path := config.IPRegionsPath
+	if path == "" {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(ipRegionCheckInterval)
+		defer ticker.Stop()
+
+		var imported time.Time
+		for {
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("读取 IP 地址库失败: %v", err)
+			} else if !info.ModTime().Equal(imported) {
+				count, err := model.ImportIPRegionsFromFile(path)
+				if err != nil {
+					log.Printf("IP 地址库导入失败: %v", err)
+				} else {
+					imported = info.ModTime()
+					log.Printf("IP 地址库导入完成: %d 个网段", count)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
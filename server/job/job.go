@@ -0,0 +1,23 @@
+package job
+
+import (
+	"klik/server/config"
+	"log"
+)
+
+// Start 启动后台定时任务，未启用数据库时不启动
+func Start() {
+	if !config.UseDB || config.DB == nil {
+		log.Println("未启用数据库，跳过后台任务")
+		return
+	}
+
+	startVideoPurgeJob()
+	startScheduledPublishJob()
+	startDraftGCJob()
+	startTrendingJob()
+	startVideoNeighborsJob()
+	startIPRegionImportJob()
+
+	log.Println("后台任务已启动")
+}
@@ -0,0 +1,31 @@
+package job
+
+import (
+	"klik/server/config"
+	"klik/server/model"
+	"log"
+	"time"
+)
+
+// startScheduledPublishJob 定期发布已到发布时间的定时视频
+func startScheduledPublishJob() {
+	interval := time.Duration(config.AppConfig.Video.PublishIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			published, err := model.PublishDueVideosFromDB()
+			if err != nil {
+				log.Printf("定时发布失败: %v", err)
+			} else if published > 0 {
+				log.Printf("定时发布完成: 发布 %d 个视频", published)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,31 @@
+package job
+
+import (
+	"klik/server/config"
+	"klik/server/model"
+	"log"
+	"time"
+)
+
+// startVideoPurgeJob 定期彻底删除超过回收站保留期的视频
+func startVideoPurgeJob() {
+	interval := time.Duration(config.AppConfig.Video.PurgeIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := model.PurgeExpiredVideosFromDB()
+			if err != nil {
+				log.Printf("回收站清理失败: %v", err)
+			} else if purged > 0 {
+				log.Printf("回收站清理完成: 删除 %d 个视频", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
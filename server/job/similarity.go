@@ -0,0 +1,35 @@
+package job
+
+import (
+	"klik/server/config"
+	"klik/server/model"
+	"log"
+	"time"
+)
+
+// startVideoNeighborsJob 定期根据用户共同互动重新计算相似视频，并重建视频内容特征以补齐导入的数据
+func startVideoNeighborsJob() {
+	interval := time.Duration(config.AppConfig.Similarity.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 6 * time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := model.RebuildVideoContentFeaturesFromDB(); err != nil {
+				log.Printf("视频内容特征重建失败: %v", err)
+			}
+
+			count, err := model.RefreshVideoNeighborsFromDB()
+			if err != nil {
+				log.Printf("相似视频计算失败: %v", err)
+			} else {
+				log.Printf("相似视频计算完成: %d 个视频有相似视频", count)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,28 @@
+package job
+
+import (
+	"klik/server/config"
+	"klik/server/model"
+	"log"
+	"time"
+)
+
+// startTrendingJob 定期重新计算视频和话题热榜
+func startTrendingJob() {
+	interval := time.Duration(config.AppConfig.Trending.IntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := model.RefreshTrendingFromDB(); err != nil {
+				log.Printf("热榜计算失败: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
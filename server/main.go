@@ -2,6 +2,8 @@ package main
 
 import (
 	"klik/server/config"
+	"klik/server/job"
+	"klik/server/model"
 	"klik/server/router"
 	"log"
 )
@@ -9,6 +11,12 @@ import (
 func main() {
 	// 初始化配置
 	config.Init()
+	if err := model.ValidateExperimentStrategies(); err != nil {
+		log.Fatalf("实验配置无效: %v", err)
+	}
+
+	// 启动后台任务
+	job.Start()
 
 	// 初始化路由
 	r := router.InitRouter()
@@ -0,0 +1,24 @@
+package media
+
+import "mime"
+
+// contentTypes 上传文件扩展名对应的 Content-Type，部分系统的 MIME 表中缺少这些类型
+var contentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mp3":  "audio/mpeg",
+	".aac":  "audio/aac",
+	".m4a":  "audio/mp4",
+	".vtt":  "text/vtt; charset=utf-8",
+}
+
+// RegisterContentTypes 注册媒体文件的 Content-Type，供静态文件服务使用
+func RegisterContentTypes() error {
+	for ext, contentType := range contentTypes {
+		if err := mime.AddExtensionType(ext, contentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+// Package media 提供不依赖外部工具的音视频文件解析
+package media
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupportedFormat 不支持的文件格式
+	ErrUnsupportedFormat = errors.New("不支持的文件格式")
+	// ErrNoDuration 文件中没有可识别的时长信息
+	ErrNoDuration = errors.New("无法识别文件时长")
+)
+
+// Duration 按扩展名解析音视频文件时长，支持 MP3、AAC（ADTS）以及 M4A/MP4/MOV
+func Duration(path string) (time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return mp3Duration(file)
+	case ".aac":
+		return adtsDuration(file)
+	case ".m4a", ".mp4", ".mov":
+		return mp4Duration(file)
+	default:
+		return 0, ErrUnsupportedFormat
+	}
+}
+
+// samplesDuration 将采样数换算为时长
+func samplesDuration(samples float64) time.Duration {
+	return time.Duration(samples * float64(time.Second))
+}
+
+// skipID3v2 跳过文件开头的 ID3v2 标签
+func skipID3v2(r *bufio.Reader) error {
+	header, err := r.Peek(10)
+	if err != nil || string(header[:3]) != "ID3" {
+		return nil
+	}
+	// 标签长度为 4 个 7 位的同步安全整数，不包含 10 字节头部
+	size := int(header[6]&0x7f)<<21 | int(header[7]&0x7f)<<14 | int(header[8]&0x7f)<<7 | int(header[9]&0x7f)
+	size += 10
+	if header[5]&0x10 != 0 {
+		size += 10 // 标签尾部
+	}
+	_, err = r.Discard(size)
+	return err
+}
+
+// MP3 码率表（kbps），下标依次为 MPEG 版本（1 或 2/2.5）、层（1-3）、码率索引
+var mp3Bitrates = [2][3][16]int{
+	{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+	},
+	{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// MP3 采样率表，下标为版本位（0: MPEG2.5, 2: MPEG2, 3: MPEG1）和采样率索引
+var mp3SampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{0, 0, 0},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// mp3Frame 解析 MP3 帧头，返回帧长度、采样数和采样率，不是合法帧头时 ok 为 false
+func mp3Frame(h []byte) (length, samples, sampleRate int, ok bool) {
+	if h[0] != 0xff || h[1]&0xe0 != 0xe0 {
+		return 0, 0, 0, false
+	}
+	version := int(h[1]>>3) & 0x03
+	layerBits := int(h[1]>>1) & 0x03
+	bitrateIndex := int(h[2] >> 4)
+	rateIndex := int(h[2]>>2) & 0x03
+	padding := int(h[2]>>1) & 0x01
+	if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return 0, 0, 0, false
+	}
+
+	layer := 4 - layerBits // 1、2、3 层
+	table := 0
+	if version != 3 {
+		table = 1
+	}
+	bitrate := mp3Bitrates[table][layer-1][bitrateIndex] * 1000
+	sampleRate = mp3SampleRates[version][rateIndex]
+
+	switch {
+	case layer == 1:
+		samples = 384
+		length = (12*bitrate/sampleRate + padding) * 4
+	case layer == 3 && version != 3:
+		samples = 576
+		length = 72*bitrate/sampleRate + padding
+	default:
+		samples = 1152
+		length = 144*bitrate/sampleRate + padding
+	}
+	return length, samples, sampleRate, length > 4
+}
+
+// mp3Duration 逐帧累加采样数计算 MP3 时长，兼容固定码率和可变码率
+func mp3Duration(file io.Reader) (time.Duration, error) {
+	r := bufio.NewReader(file)
+	if err := skipID3v2(r); err != nil {
+		return 0, ErrNoDuration
+	}
+
+	var seconds float64
+	frames := 0
+	for {
+		header, err := r.Peek(4)
+		if err != nil {
+			break
+		}
+		length, samples, sampleRate, ok := mp3Frame(header)
+		if !ok {
+			// 非帧数据（如 ID3v1 标签或损坏部分），逐字节重新同步
+			if _, err := r.Discard(1); err != nil {
+				break
+			}
+			continue
+		}
+		seconds += float64(samples) / float64(sampleRate)
+		frames++
+		if _, err := r.Discard(length); err != nil {
+			break
+		}
+	}
+
+	if frames == 0 {
+		return 0, ErrNoDuration
+	}
+	return samplesDuration(seconds), nil
+}
+
+// ADTS 采样率表
+var adtsSampleRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// adtsDuration 逐帧累加 ADTS 帧的采样数计算 AAC 时长
+func adtsDuration(file io.Reader) (time.Duration, error) {
+	r := bufio.NewReader(file)
+	if err := skipID3v2(r); err != nil {
+		return 0, ErrNoDuration
+	}
+
+	var seconds float64
+	frames := 0
+	for {
+		h, err := r.Peek(7)
+		if err != nil {
+			break
+		}
+		// 同步字 0xFFF，层固定为 0
+		if h[0] != 0xff || h[1]&0xf6 != 0xf0 {
+			if _, err := r.Discard(1); err != nil {
+				break
+			}
+			continue
+		}
+		rateIndex := int(h[2]>>2) & 0x0f
+		length := int(h[3]&0x03)<<11 | int(h[4])<<3 | int(h[5]>>5)
+		blocks := int(h[6]&0x03) + 1
+		if rateIndex >= len(adtsSampleRates) || length < 7 {
+			if _, err := r.Discard(1); err != nil {
+				break
+			}
+			continue
+		}
+		seconds += float64(1024*blocks) / float64(adtsSampleRates[rateIndex])
+		frames++
+		if _, err := r.Discard(length); err != nil {
+			break
+		}
+	}
+
+	if frames == 0 {
+		return 0, ErrNoDuration
+	}
+	return samplesDuration(seconds), nil
+}
+
+// mp4Duration 读取 MP4 容器 moov/mvhd 中的时长
+func mp4Duration(file io.ReadSeeker) (time.Duration, error) {
+	end, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("读取文件失败: %v", err)
+	}
+
+	moovStart, moovEnd, err := findMP4Box(file, 0, end, "moov")
+	if err != nil {
+		return 0, err
+	}
+	mvhdStart, _, err := findMP4Box(file, moovStart, moovEnd, "mvhd")
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := file.Seek(mvhdStart, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("读取文件失败: %v", err)
+	}
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(file, buf[:4]); err != nil {
+		return 0, ErrNoDuration
+	}
+
+	var timescale, duration uint64
+	if buf[0] == 1 {
+		// 版本 1：创建时间、修改时间各 8 字节，时长 8 字节
+		if _, err := io.ReadFull(file, buf[:28]); err != nil {
+			return 0, ErrNoDuration
+		}
+		timescale = uint64(binary.BigEndian.Uint32(buf[16:20]))
+		duration = binary.BigEndian.Uint64(buf[20:28])
+	} else {
+		if _, err := io.ReadFull(file, buf[:16]); err != nil {
+			return 0, ErrNoDuration
+		}
+		timescale = uint64(binary.BigEndian.Uint32(buf[8:12]))
+		duration = uint64(binary.BigEndian.Uint32(buf[12:16]))
+	}
+
+	if timescale == 0 {
+		return 0, ErrNoDuration
+	}
+	return samplesDuration(float64(duration) / float64(timescale)), nil
+}
+
+// findMP4Box 在 [start, end) 范围内查找指定类型的盒子，返回盒子内容的起止位置
+func findMP4Box(file io.ReadSeeker, start, end int64, boxType string) (int64, int64, error) {
+	header := make([]byte, 16)
+	for pos := start; pos+8 <= end; {
+		if _, err := file.Seek(pos, io.SeekStart); err != nil {
+			return 0, 0, fmt.Errorf("读取文件失败: %v", err)
+		}
+		if _, err := io.ReadFull(file, header[:8]); err != nil {
+			return 0, 0, ErrNoDuration
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - pos // 盒子延伸到范围末尾
+		case 1:
+			if _, err := io.ReadFull(file, header[8:16]); err != nil {
+				return 0, 0, ErrNoDuration
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || pos+size > end {
+			return 0, 0, ErrNoDuration
+		}
+
+		if string(header[4:8]) == boxType {
+			return pos + headerSize, pos + size, nil
+		}
+		pos += size
+	}
+	return 0, 0, ErrNoDuration
+}
@@ -0,0 +1,227 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mp3Fixture 生成 ID3v2 标签、frames 个 MPEG1 Layer3 128kbps 44.1kHz 帧和 ID3v1 标签
+func mp3Fixture(frames int) []byte {
+	var buf bytes.Buffer
+	// ID3v2 头部，标签内容 20 字节
+	buf.Write([]byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20})
+	buf.Write(make([]byte, 20))
+	for i := 0; i < frames; i++ {
+		frame := make([]byte, 417) // 144 * 128000 / 44100
+		copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+		buf.Write(frame)
+	}
+	buf.WriteString("TAG")
+	buf.Write(make([]byte, 125))
+	return buf.Bytes()
+}
+
+// adtsFixture 生成 frames 个 44.1kHz 的 ADTS 帧，每帧一个 1024 采样的数据块
+func adtsFixture(frames int) []byte {
+	const length = 100
+	var buf bytes.Buffer
+	for i := 0; i < frames; i++ {
+		frame := make([]byte, length)
+		copy(frame, []byte{
+			0xff, 0xf1, // 同步字、MPEG-4、无 CRC
+			0x50,                         // AAC LC，采样率索引 4（44100）
+			0x80 | byte(length>>11)&0x03, // 双声道，帧长度高 2 位
+			byte(length >> 3),
+			byte(length&0x07)<<5 | 0x1f,
+			0xfc, // 1 个数据块
+		})
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+// mp4Box 构建 MP4 盒子
+func mp4Box(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(box, uint32(8+len(body)))
+	copy(box[4:], boxType)
+	return append(box, body...)
+}
+
+// mvhdV0 构建版本 0 的 mvhd 内容
+func mvhdV0(timescale, duration uint32) []byte {
+	payload := make([]byte, 100)
+	binary.BigEndian.PutUint32(payload[12:], timescale)
+	binary.BigEndian.PutUint32(payload[16:], duration)
+	return payload
+}
+
+// mvhdV1 构建版本 1 的 mvhd 内容
+func mvhdV1(timescale uint32, duration uint64) []byte {
+	payload := make([]byte, 112)
+	payload[0] = 1
+	binary.BigEndian.PutUint32(payload[20:], timescale)
+	binary.BigEndian.PutUint64(payload[24:], duration)
+	return payload
+}
+
+// largeMdat 构建使用 64 位长度的 mdat 盒子
+func largeMdat(n int) []byte {
+	box := make([]byte, 16+n)
+	binary.BigEndian.PutUint32(box, 1)
+	copy(box[4:], "mdat")
+	binary.BigEndian.PutUint64(box[8:], uint64(16+n))
+	return box
+}
+
+// within 判断时长误差不超过 1 毫秒
+func within(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Millisecond && diff < time.Millisecond
+}
+
+func TestMP3Duration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    time.Duration
+		wantErr error
+	}{
+		{"frames with id3 tags", mp3Fixture(100), samplesDuration(100 * 1152 / 44100.0), nil},
+		{"single frame", mp3Fixture(1), samplesDuration(1152 / 44100.0), nil},
+		{"no frames", mp3Fixture(0), 0, ErrNoDuration},
+		{"empty", nil, 0, ErrNoDuration},
+	}
+	for _, tt := range tests {
+		got, err := mp3Duration(bytes.NewReader(tt.data))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: mp3Duration error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !within(got, tt.want) {
+			t.Errorf("%s: mp3Duration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMP3Frame(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     []byte
+		wantLength int
+		wantRate   int
+		wantOK     bool
+	}{
+		{"mpeg1 layer3 128k", []byte{0xff, 0xfb, 0x90, 0x00}, 417, 44100, true},
+		{"mpeg1 layer3 128k padded", []byte{0xff, 0xfb, 0x92, 0x00}, 418, 44100, true},
+		{"mpeg2 layer3 64k 22.05k", []byte{0xff, 0xf3, 0x80, 0x00}, 208, 22050, true},
+		{"no sync", []byte{0x00, 0xfb, 0x90, 0x00}, 0, 0, false},
+		{"reserved version", []byte{0xff, 0xeb, 0x90, 0x00}, 0, 0, false},
+		{"free bitrate", []byte{0xff, 0xfb, 0x00, 0x00}, 0, 0, false},
+		{"reserved sample rate", []byte{0xff, 0xfb, 0x9c, 0x00}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		length, _, rate, ok := mp3Frame(tt.header)
+		if ok != tt.wantOK || (ok && (length != tt.wantLength || rate != tt.wantRate)) {
+			t.Errorf("%s: mp3Frame = %d, %d, %v, want %d, %d, %v",
+				tt.name, length, rate, ok, tt.wantLength, tt.wantRate, tt.wantOK)
+		}
+	}
+}
+
+func TestADTSDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    time.Duration
+		wantErr error
+	}{
+		{"frames", adtsFixture(430), samplesDuration(430 * 1024 / 44100.0), nil},
+		{"leading garbage", append([]byte{0x00, 0x01, 0x02}, adtsFixture(10)...), samplesDuration(10 * 1024 / 44100.0), nil},
+		{"no frames", []byte("not an aac file"), 0, ErrNoDuration},
+	}
+	for _, tt := range tests {
+		got, err := adtsDuration(bytes.NewReader(tt.data))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: adtsDuration error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !within(got, tt.want) {
+			t.Errorf("%s: adtsDuration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMP4Duration(t *testing.T) {
+	ftyp := mp4Box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41"))
+	// moov 长度为 0 表示延伸到文件末尾
+	openMoov := mp4Box("moov", mp4Box("mvhd", mvhdV0(600, 600*5)))
+	binary.BigEndian.PutUint32(openMoov, 0)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    time.Duration
+		wantErr error
+	}{
+		{"version 0", bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("mvhd", mvhdV0(1000, 12345)))}, nil), 12345 * time.Millisecond, nil},
+		{"version 1", bytes.Join([][]byte{ftyp, mp4Box("moov", mp4Box("mvhd", mvhdV1(90000, 90000*61)))}, nil), 61 * time.Second, nil},
+		{"moov after large mdat", bytes.Join([][]byte{ftyp, largeMdat(64), mp4Box("moov", mp4Box("trak"), mp4Box("mvhd", mvhdV0(48000, 48000*3/2)))}, nil), 1500 * time.Millisecond, nil},
+		{"moov to end of file", bytes.Join([][]byte{ftyp, openMoov}, nil), 5 * time.Second, nil},
+		{"zero timescale", mp4Box("moov", mp4Box("mvhd", mvhdV0(0, 100))), 0, ErrNoDuration},
+		{"no moov", bytes.Join([][]byte{ftyp, largeMdat(8)}, nil), 0, ErrNoDuration},
+		{"truncated box", ftyp[:len(ftyp)-1], 0, ErrNoDuration},
+	}
+	for _, tt := range tests {
+		got, err := mp4Duration(bytes.NewReader(tt.data))
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: mp4Duration error = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !within(got, tt.want) {
+			t.Errorf("%s: mp4Duration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDurationByExtension(t *testing.T) {
+	dir := t.TempDir()
+	mp4 := mp4Box("moov", mp4Box("mvhd", mvhdV0(1000, 2000)))
+
+	tests := []struct {
+		file    string
+		data    []byte
+		want    time.Duration
+		wantErr error
+	}{
+		{"a.MP3", mp3Fixture(10), samplesDuration(10 * 1152 / 44100.0), nil},
+		{"a.aac", adtsFixture(10), samplesDuration(10 * 1024 / 44100.0), nil},
+		{"a.m4a", mp4, 2 * time.Second, nil},
+		{"a.mov", mp4, 2 * time.Second, nil},
+		{"a.wav", mp4, 0, ErrUnsupportedFormat},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		if err := os.WriteFile(path, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Duration(path)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Duration(%s) error = %v, want %v", tt.file, err, tt.wantErr)
+			continue
+		}
+		if !within(got, tt.want) {
+			t.Errorf("Duration(%s) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	if _, err := Duration(filepath.Join(dir, "missing.mp3")); err == nil {
+		t.Error("Duration of missing file succeeded, want error")
+	}
+}
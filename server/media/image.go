@@ -0,0 +1,150 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// 注册 GIF、PNG 解码器，JPEG 已由 image/jpeg 注册
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxImagePixels 允许解码的最大像素数，防止超大图片占满内存
+const MaxImagePixels = 40 << 20
+
+// ErrImageTooLarge 图片尺寸过大
+var ErrImageTooLarge = errors.New("图片尺寸过大")
+
+// DecodeImage 解码 JPEG、PNG 或 GIF 图片，解码前先检查尺寸
+func DecodeImage(r io.ReadSeeker) (image.Image, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("读取图片失败: %v", err)
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("解码图片失败: %v", err)
+	}
+	return img, nil
+}
+
+// ResizeImage 将图片等比缩小到宽度不超过 maxWidth，使用区域平均采样；图片本身更小时不放大
+func ResizeImage(img image.Image, maxWidth int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	if maxWidth <= 0 || srcW <= maxWidth {
+		return src
+	}
+	dstW := maxWidth
+	dstH := srcH * dstW / srcW
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, (y+1)*srcH/dstH
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, (x+1)*srcW/dstW
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// 对源图中对应区域的像素取平均
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(src.Pix[offset])
+					g += uint32(src.Pix[offset+1])
+					b += uint32(src.Pix[offset+2])
+					a += uint32(src.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG 将图片编码为 JPEG，透明部分按白色背景处理
+func EncodeJPEG(w io.Writer, img *image.RGBA, quality int) error {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	draw.Draw(flat, bounds, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, bounds, img, bounds.Min, draw.Over)
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+}
+
+// ImageSize 读取图片宽高，支持 JPEG、PNG、GIF 和 WebP，不解码像素数据
+func ImageSize(r io.ReadSeeker) (int, int, error) {
+	header := make([]byte, 30)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, 0, ErrUnsupportedFormat
+	}
+	header = header[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("读取图片失败: %v", err)
+	}
+
+	if len(header) >= 12 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP" {
+		return webpSize(header)
+	}
+
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, 0, ErrUnsupportedFormat
+	}
+	return config.Width, config.Height, nil
+}
+
+// webpSize 从 WebP 文件头解析宽高，header 至少包含前 30 字节
+func webpSize(h []byte) (int, int, error) {
+	if len(h) < 30 {
+		return 0, 0, ErrUnsupportedFormat
+	}
+	switch string(h[12:16]) {
+	case "VP8 ":
+		// 有损格式：关键帧起始码之后是 14 位宽高
+		width := int(binary.LittleEndian.Uint16(h[26:28]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(h[28:30]) & 0x3fff)
+		return width, height, nil
+	case "VP8L":
+		// 无损格式：签名 0x2f 之后是 14 位的宽减一和高减一
+		b := h[21:25]
+		width := 1 + (int(b[0]) | int(b[1]&0x3f)<<8)
+		height := 1 + (int(b[1]>>6) | int(b[2])<<2 | int(b[3]&0x0f)<<10)
+		return width, height, nil
+	case "VP8X":
+		// 扩展格式：24 位的宽减一和高减一
+		width := 1 + (int(h[24]) | int(h[25])<<8 | int(h[26])<<16)
+		height := 1 + (int(h[27]) | int(h[28])<<8 | int(h[29])<<16)
+		return width, height, nil
+	}
+	return 0, 0, ErrUnsupportedFormat
+}
@@ -0,0 +1,199 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSubtitleCues 字幕最多包含的条目数
+const MaxSubtitleCues = 20000
+
+// ErrInvalidSubtitle 字幕文件格式错误
+var ErrInvalidSubtitle = errors.New("字幕文件格式错误")
+
+// subtitleCue 一条字幕
+type subtitleCue struct {
+	ID       string
+	Start    time.Duration
+	End      time.Duration
+	Settings string // WebVTT 的位置、对齐等设置
+	Lines    []string
+}
+
+// NormalizeSubtitle 校验 SRT 或 WebVTT 字幕并统一转换为 WebVTT，
+// 根据内容是否以 WEBVTT 开头判断格式，NOTE/STYLE/REGION 块会被丢弃
+func NormalizeSubtitle(data []byte) ([]byte, error) {
+	text := string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	// 去掉行尾空白，避免只含空格的行不被识别为块分隔
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = strings.Join(lines, "\n")
+
+	cues, err := parseSubtitleBlocks(text, isWebVTT(text))
+	if err != nil {
+		return nil, err
+	}
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("%w: 没有字幕内容", ErrInvalidSubtitle)
+	}
+	if len(cues) > MaxSubtitleCues {
+		return nil, fmt.Errorf("%w: 字幕条目超过 %d 条", ErrInvalidSubtitle, MaxSubtitleCues)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n")
+	for _, cue := range cues {
+		buf.WriteString("\n")
+		if cue.ID != "" {
+			buf.WriteString(cue.ID + "\n")
+		}
+		buf.WriteString(formatVTTTime(cue.Start) + " --> " + formatVTTTime(cue.End))
+		if cue.Settings != "" {
+			buf.WriteString(" " + cue.Settings)
+		}
+		buf.WriteString("\n")
+		for _, line := range cue.Lines {
+			buf.WriteString(line + "\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// isWebVTT 判断文本是否以 WEBVTT 签名开头
+func isWebVTT(text string) bool {
+	if !strings.HasPrefix(text, "WEBVTT") {
+		return false
+	}
+	rest := text[len("WEBVTT"):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n'
+}
+
+// parseSubtitleBlocks 按空行拆分字幕块并解析，vtt 为 false 时按 SRT 解析
+func parseSubtitleBlocks(text string, vtt bool) ([]subtitleCue, error) {
+	blocks := strings.Split(text, "\n\n")
+	cues := []subtitleCue{}
+	for i, block := range blocks {
+		block = strings.Trim(block, "\n")
+		if block == "" {
+			continue
+		}
+		lines := strings.Split(block, "\n")
+
+		if vtt {
+			// 第一块为文件头，其余非字幕块直接跳过
+			if i == 0 {
+				continue
+			}
+			if lines[0] == "NOTE" || strings.HasPrefix(lines[0], "NOTE ") || strings.HasPrefix(lines[0], "NOTE\t") ||
+				lines[0] == "STYLE" || lines[0] == "REGION" {
+				continue
+			}
+		}
+
+		cue := subtitleCue{}
+		// 时间行之前可以有一行编号（SRT）或标识（WebVTT）
+		if !strings.Contains(lines[0], "-->") {
+			cue.ID = strings.TrimSpace(lines[0])
+			lines = lines[1:]
+			if !vtt {
+				if _, err := strconv.Atoi(cue.ID); err != nil {
+					return nil, fmt.Errorf("%w: 第 %d 段编号无效", ErrInvalidSubtitle, i+1)
+				}
+			}
+		}
+		if len(lines) == 0 {
+			return nil, fmt.Errorf("%w: 第 %d 段缺少时间", ErrInvalidSubtitle, i+1)
+		}
+
+		start, end, settings, err := parseCueTiming(lines[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: 第 %d 段%v", ErrInvalidSubtitle, i+1, err)
+		}
+		cue.Start, cue.End = start, end
+		if vtt {
+			cue.Settings = settings
+		}
+
+		for _, line := range lines[1:] {
+			// 正文中的 --> 会被识别为时间行，需要转义
+			cue.Lines = append(cue.Lines, strings.ReplaceAll(line, "-->", "--&gt;"))
+		}
+		cues = append(cues, cue)
+	}
+	return cues, nil
+}
+
+// parseCueTiming 解析“开始 --> 结束 [设置]”时间行
+func parseCueTiming(line string) (start, end time.Duration, settings string, err error) {
+	parts := strings.SplitN(line, "-->", 2)
+	if len(parts) != 2 {
+		return 0, 0, "", errors.New("时间格式错误")
+	}
+	start, err = parseSubtitleTime(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, "", err
+	}
+
+	fields := strings.Fields(parts[1])
+	if len(fields) == 0 {
+		return 0, 0, "", errors.New("缺少结束时间")
+	}
+	end, err = parseSubtitleTime(fields[0])
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if end <= start {
+		return 0, 0, "", errors.New("结束时间必须晚于开始时间")
+	}
+	return start, end, strings.Join(fields[1:], " "), nil
+}
+
+// parseSubtitleTime 解析 [hh:]mm:ss.ttt 或 SRT 的 hh:mm:ss,ttt 时间
+func parseSubtitleTime(value string) (time.Duration, error) {
+	value = strings.Replace(value, ",", ".", 1)
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("时间 %q 格式错误", value)
+	}
+
+	secParts := strings.SplitN(parts[len(parts)-1], ".", 2)
+	if len(secParts) != 2 || len(secParts[1]) != 3 {
+		return 0, fmt.Errorf("时间 %q 格式错误", value)
+	}
+
+	numbers := append(parts[:len(parts)-1:len(parts)-1], secParts...)
+	values := make([]int, len(numbers))
+	for i, number := range numbers {
+		n, err := strconv.Atoi(number)
+		if err != nil || n < 0 || number == "" || strings.ContainsAny(number, "+-") {
+			return 0, fmt.Errorf("时间 %q 格式错误", value)
+		}
+		values[i] = n
+	}
+
+	var hours, minutes, seconds, millis int
+	if len(parts) == 3 {
+		hours, minutes, seconds, millis = values[0], values[1], values[2], values[3]
+	} else {
+		minutes, seconds, millis = values[0], values[1], values[2]
+	}
+	if minutes > 59 || seconds > 59 {
+		return 0, fmt.Errorf("时间 %q 格式错误", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(millis)*time.Millisecond, nil
+}
+
+// formatVTTTime 格式化为 WebVTT 的 hh:mm:ss.ttt
+func formatVTTTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
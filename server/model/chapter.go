@@ -0,0 +1,238 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// 章节限制
+const (
+	MaxChapterTitleLength = 50
+	MaxVideoChapters      = 100
+	minDescChapters       = 2 // 描述中至少有两个时间点才生成章节
+)
+
+// 章节来源，对应 video_chapters.source
+const (
+	ChapterSourceDesc   = 0 // 从描述解析
+	ChapterSourceManual = 1 // 作者手动设置，优先于描述
+)
+
+// ErrInvalidChapters 章节参数无效
+var ErrInvalidChapters = errors.New("章节参数无效")
+
+// chapterLinePattern 描述中的章节行，如“00:00 开场”“1:02:30 - 总结”
+var chapterLinePattern = regexp.MustCompile(`^\s*(?:(\d{1,2}):)?(\d{1,2}):(\d{2})(?:\s*[-–—:：]\s*|\s+)(\S.*?)\s*$`)
+
+// Chapter 视频章节
+type Chapter struct {
+	StartMs int    `json:"start_ms"`
+	EndMs   int    `json:"end_ms"` // 下一章节开始时间，最后一章为视频时长（时长未知或不晚于开始时间时为 0）
+	Title   string `json:"title"`
+}
+
+// ChapterParams 设置章节参数
+type ChapterParams struct {
+	StartMs int    `json:"start_ms"`
+	Title   string `json:"title"`
+}
+
+// parseDescChapters 从描述中逐行解析章节，要求第一个时间点为 00:00 且时间递增，不满足时返回 nil
+func parseDescChapters(desc string) []ChapterParams {
+	var chapters []ChapterParams
+	for _, line := range strings.Split(desc, "\n") {
+		match := chapterLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		hours, _ := strconv.Atoi(match[1])
+		minutes, _ := strconv.Atoi(match[2])
+		seconds, _ := strconv.Atoi(match[3])
+		if seconds > 59 || (match[1] != "" && minutes > 59) {
+			continue
+		}
+		chapters = append(chapters, ChapterParams{
+			StartMs: ((hours*60+minutes)*60 + seconds) * 1000,
+			Title:   match[4],
+		})
+	}
+
+	if len(chapters) < minDescChapters || chapters[0].StartMs != 0 {
+		return nil
+	}
+	return chapters
+}
+
+// validateChapters 校验章节时间递增且在视频时长内，duration 为视频时长（毫秒），0 表示时长未知
+func validateChapters(chapters []ChapterParams, duration int) error {
+	if len(chapters) > MaxVideoChapters {
+		return fmt.Errorf("%w: 最多 %d 个章节", ErrInvalidChapters, MaxVideoChapters)
+	}
+	for i := range chapters {
+		chapters[i].Title = strings.TrimSpace(chapters[i].Title)
+		title := chapters[i].Title
+		if title == "" || utf8.RuneCountInString(title) > MaxChapterTitleLength {
+			return fmt.Errorf("%w: 第 %d 个章节标题不能为空且不超过 %d 个字符", ErrInvalidChapters, i+1, MaxChapterTitleLength)
+		}
+		if chapters[i].StartMs < 0 || (i > 0 && chapters[i].StartMs <= chapters[i-1].StartMs) {
+			return fmt.Errorf("%w: 章节开始时间必须递增", ErrInvalidChapters)
+		}
+		if duration > 0 && chapters[i].StartMs >= duration {
+			return fmt.Errorf("%w: 第 %d 个章节超出视频时长", ErrInvalidChapters, i+1)
+		}
+	}
+	return nil
+}
+
+// replaceVideoChapters 覆盖保存视频章节
+func replaceVideoChapters(tx *sql.Tx, videoID int64, chapters []ChapterParams, source int) error {
+	if _, err := tx.Exec(`DELETE FROM video_chapters WHERE video_id = $1`, videoID); err != nil {
+		return fmt.Errorf("清除视频章节失败: %v", err)
+	}
+	for _, chapter := range chapters {
+		_, err := tx.Exec(`
+			INSERT INTO video_chapters (video_id, start_ms, title, source) VALUES ($1, $2, $3, $4)
+		`, videoID, chapter.StartMs, chapter.Title, source)
+		if err != nil {
+			return fmt.Errorf("保存视频章节失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// saveDescChapters 根据描述重新生成章节，作者手动设置过章节时保持不变。
+// 描述中的章节不合法（如超出时长）时不生成章节，不影响视频发布
+func saveDescChapters(tx *sql.Tx, videoID int64, desc string) error {
+	var duration int
+	var manual bool
+	err := tx.QueryRow(`
+		SELECT COALESCE(v.duration, 0),
+		       EXISTS (SELECT 1 FROM video_chapters WHERE video_id = v.id AND source = $2)
+		FROM videos v WHERE v.id = $1
+	`, videoID, ChapterSourceManual).Scan(&duration, &manual)
+	if err != nil {
+		return fmt.Errorf("查询视频章节失败: %v", err)
+	}
+	if manual {
+		return nil
+	}
+
+	chapters := parseDescChapters(desc)
+	if validateChapters(chapters, duration) != nil {
+		chapters = nil
+	}
+	return replaceVideoChapters(tx, videoID, chapters, ChapterSourceDesc)
+}
+
+// SetVideoChaptersFromDB 作者手动设置章节，传空列表时恢复为从描述解析
+func SetVideoChaptersFromDB(awemeID, userID string, chapters []ChapterParams) ([]Chapter, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVideoNotDeleted(tx, videoID); err != nil {
+		return nil, err
+	}
+
+	var desc string
+	var duration int
+	err = tx.QueryRow(`
+		SELECT COALESCE(video_desc, ''), COALESCE(duration, 0) FROM videos WHERE id = $1 FOR UPDATE
+	`, videoID).Scan(&desc, &duration)
+	if err != nil {
+		return nil, fmt.Errorf("查询视频失败: %v", err)
+	}
+
+	if len(chapters) == 0 {
+		if _, err := tx.Exec(`DELETE FROM video_chapters WHERE video_id = $1`, videoID); err != nil {
+			return nil, fmt.Errorf("清除视频章节失败: %v", err)
+		}
+		if err := saveDescChapters(tx, videoID, desc); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := validateChapters(chapters, duration); err != nil {
+			return nil, err
+		}
+		if err := replaceVideoChapters(tx, videoID, chapters, ChapterSourceManual); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	videos := []Video{{AwemeID: awemeID, Duration: duration}}
+	if err := fillVideoChapters(videos); err != nil {
+		return nil, err
+	}
+	return videos[0].Chapters, nil
+}
+
+// fillVideoChapters 填充视频列表的章节，并计算每章的结束时间
+func fillVideoChapters(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, vc.start_ms, vc.title
+		FROM video_chapters vc
+		JOIN videos v ON vc.video_id = v.id
+		WHERE v.aweme_id = ANY($1)
+		ORDER BY vc.start_ms
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频章节失败: %v", err)
+	}
+	defer rows.Close()
+
+	chapters := make(map[string][]Chapter)
+	for rows.Next() {
+		var awemeID string
+		var chapter Chapter
+		if err := rows.Scan(&awemeID, &chapter.StartMs, &chapter.Title); err != nil {
+			return fmt.Errorf("解析视频章节失败: %v", err)
+		}
+		chapters[awemeID] = append(chapters[awemeID], chapter)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频章节时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		list := chapters[videos[i].AwemeID]
+		for j := range list {
+			if j+1 < len(list) {
+				list[j].EndMs = list[j+1].StartMs
+			} else if videos[i].Duration > list[j].StartMs {
+				list[j].EndMs = videos[i].Duration
+			}
+		}
+		if list == nil {
+			list = []Chapter{}
+		}
+		videos[i].Chapters = list
+	}
+	return nil
+}
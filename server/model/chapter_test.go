@@ -0,0 +1,67 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDescChapters(t *testing.T) {
+	tests := []struct {
+		name string
+		desc string
+		want []ChapterParams
+	}{
+		{
+			name: "minutes and hours",
+			desc: "今天聊聊旅行\n00:00 开场\n1:30 - 行程\n1:02:03：总结",
+			want: []ChapterParams{{0, "开场"}, {90000, "行程"}, {3723000, "总结"}},
+		},
+		{
+			name: "not starting at zero",
+			desc: "00:10 开场\n01:00 正文",
+		},
+		{
+			name: "single timestamp",
+			desc: "00:00 开场",
+		},
+		{
+			name: "invalid seconds skipped",
+			desc: "00:00 开场\n00:75 无效\n02:00 正文",
+			want: []ChapterParams{{0, "开场"}, {120000, "正文"}},
+		},
+		{
+			name: "timestamp without title",
+			desc: "00:00\n01:00",
+		},
+	}
+	for _, tt := range tests {
+		if got := parseDescChapters(tt.desc); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: parseDescChapters = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateChapters(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters []ChapterParams
+		duration int
+		wantErr  bool
+	}{
+		{"valid", []ChapterParams{{0, "开场"}, {60000, "正文"}}, 300000, false},
+		{"unknown duration", []ChapterParams{{0, "开场"}, {600000, "正文"}}, 0, false},
+		{"beyond duration", []ChapterParams{{0, "开场"}, {300000, "正文"}}, 300000, true},
+		{"not increasing", []ChapterParams{{0, "开场"}, {0, "正文"}}, 300000, true},
+		{"empty title", []ChapterParams{{0, " "}}, 300000, true},
+	}
+	for _, tt := range tests {
+		err := validateChapters(tt.chapters, tt.duration)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateChapters error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidChapters) {
+			t.Errorf("%s: validateChapters error = %v, want ErrInvalidChapters", tt.name, err)
+		}
+	}
+}
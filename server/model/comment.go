@@ -2,8 +2,12 @@ package model
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"klik/server/config"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // genderToInt 将性别字符串转换为整数
@@ -18,30 +22,45 @@ func genderToInt(gender string) int {
 	}
 }
 
-// 从数据库获取视频评论
-func GetVideoCommentsFromPostgres(videoID string) ([]Comment, error) {
+// boolToInt 将布尔值转换为整数
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 从数据库获取视频评论，视频对观看者不可见时返回 ErrVideoNotFound
+func GetVideoCommentsFromPostgres(videoID, viewerID string) ([]Comment, error) {
 	if config.DB == nil {
 		return nil, fmt.Errorf("数据库未初始化")
 	}
+	id, err := getVisibleVideoID(config.DB, videoID, viewerID)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
-		SELECT c.id, c.text, c.create_time, c.digg_count, c.parent_id
+		SELECT c.id, c.comment_id, c.content, COALESCE(c.create_time, 0), COALESCE(c.digg_count, 0),
+		       COALESCE(c.commenter_id, '')
 		FROM comments c
-		JOIN videos v ON c.video_id = v.id
-		WHERE v.aweme_id = $1
+		WHERE c.video_id = $1
 		ORDER BY c.create_time DESC
 	`
-	rows, err := config.DB.Query(query, videoID)
+	rows, err := config.DB.Query(query, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var comments []Comment
+	var commentIDs []int64
 	for rows.Next() {
 		var comment Comment
+		var commentID int64
 		var userID string
 		err := rows.Scan(
+			&commentID,
 			&comment.ID,
 			&comment.Text,
 			&comment.CreateTime,
@@ -62,14 +81,24 @@ func GetVideoCommentsFromPostgres(videoID string) ([]Comment, error) {
 			}
 		}
 
+		comment.TextExtra = []TextExtra{}
 		comments = append(comments, comment)
+		commentIDs = append(commentIDs, commentID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 填充评论中的 @用户
+	if err := fillCommentMentions(comments, commentIDs); err != nil {
+		return nil, err
 	}
 
 	return comments, nil
 }
 
 // GetLongRecommendVideosFromPostgres 从数据库获取长视频推荐列表
-func GetLongRecommendVideosFromPostgres(start, pageSize int) ([]Video, error) {
+func GetLongRecommendVideosFromPostgres(viewerID string, start, pageSize int) ([]Video, error) {
 	if config.DB == nil {
 		return nil, fmt.Errorf("数据库未初始化")
 	}
@@ -82,11 +111,11 @@ func GetLongRecommendVideosFromPostgres(start, pageSize int) ([]Video, error) {
 		FROM videos v
 		LEFT JOIN users u ON v.author_user_id = u.uid
 		LEFT JOIN video_statistics vs ON v.id = vs.video_id
-		WHERE v.duration > 60
+		WHERE v.duration > 60000 AND ` + videoVisibleCondition("$3") + `
 		ORDER BY v.create_time DESC
 		LIMIT $1 OFFSET $2
 	`
-	rows, err := config.DB.Query(query, pageSize, start)
+	rows, err := config.DB.Query(query, pageSize, start, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -331,5 +360,84 @@ func GetLongRecommendVideosFromPostgres(start, pageSize int) ([]Video, error) {
 		videos = append(videos, video)
 	}
 
+	if err := enrichVideos(videos); err != nil {
+		return nil, err
+	}
 	return videos, nil
 }
+
+// MaxCommentLength 评论最大字符数
+const MaxCommentLength = 500
+
+// ErrInvalidComment 评论内容无效
+var ErrInvalidComment = errors.New("评论内容不能为空且不能超过 500 字")
+
+// CreateVideoCommentFromDB 发表视频评论，评论中 @ 的用户会收到通知
+func CreateVideoCommentFromDB(awemeID, userID, text string) (Comment, error) {
+	if config.DB == nil {
+		return Comment{}, fmt.Errorf("数据库未初始化")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > MaxCommentLength {
+		return Comment{}, ErrInvalidComment
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return Comment{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	// 只能评论自己能看到的视频
+	videoID, err := getVisibleVideoID(tx, awemeID, userID)
+	if err != nil {
+		return Comment{}, err
+	}
+
+	comment := Comment{
+		ID:         newAwemeID(),
+		Text:       text,
+		CreateTime: time.Now().Unix(),
+		TextExtra:  []TextExtra{},
+	}
+	var commentID int64
+	err = tx.QueryRow(`
+		INSERT INTO comments (comment_id, aweme_id, video_id, commenter_id, content, create_time)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, comment.ID, awemeID, videoID, userID, text, comment.CreateTime).Scan(&commentID)
+	if err != nil {
+		return Comment{}, fmt.Errorf("保存评论失败: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE video_statistics SET comment_count = comment_count + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE video_id = $1
+	`, videoID)
+	if err != nil {
+		return Comment{}, fmt.Errorf("更新评论数失败: %v", err)
+	}
+
+	mentions, err := saveCommentMentions(tx, commentID, videoID, userID, awemeID, text)
+	if err != nil {
+		return Comment{}, err
+	}
+	for _, m := range mentions {
+		comment.TextExtra = append(comment.TextExtra, TextExtra{
+			Start:  m.Start,
+			End:    m.End,
+			Type:   TextExtraTypeUser,
+			UserID: m.UserID,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Comment{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	comment.User, err = GetUserByID(userID)
+	if err != nil {
+		comment.User = User{UID: userID}
+	}
+	return comment, nil
+}
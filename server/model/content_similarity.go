@@ -0,0 +1,114 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+)
+
+// videoContentFeaturesQuery 返回视频内容特征的查询，每行为 (video_id, feature, weight)，videoFilter 为视频表 v 的过滤条件。
+// 特征及权重：共同话题 3，相同音乐 2，同一作者 1，相同标签 1，相同分类标签 1；
+// video_labels 可以是逗号分隔的文本或 JSON 数组
+func videoContentFeaturesQuery(videoFilter string) string {
+	return `
+		SELECT DISTINCT v.id, 'tag:' || vh.hashtag_id, 3.0::float8
+		FROM video_hashtags vh
+		JOIN videos v ON v.id = vh.video_id
+		WHERE ` + videoFilter + `
+		UNION ALL
+		SELECT v.id, 'music:' || v.music_id, 2.0::float8
+		FROM videos v
+		WHERE v.music_id IS NOT NULL AND ` + videoFilter + `
+		UNION ALL
+		SELECT v.id, 'author:' || v.author_user_id, 1.0::float8
+		FROM videos v
+		WHERE v.author_user_id IS NOT NULL AND ` + videoFilter + `
+		UNION ALL
+		SELECT DISTINCT v.id, 'label:' || l.label, 1.0::float8
+		FROM videos v
+		CROSS JOIN LATERAL (
+			SELECT lower(btrim(part, ' "''')) AS label
+			FROM regexp_split_to_table(btrim(COALESCE(v.video_labels, ''), '[] '), '[,，]') AS part
+		) l
+		WHERE l.label <> '' AND ` + videoFilter + `
+		UNION ALL
+		SELECT v.id, 'sort:' || lower(btrim(v.sort_label)), 1.0::float8
+		FROM videos v
+		WHERE btrim(COALESCE(v.sort_label, '')) <> '' AND ` + videoFilter
+}
+
+// saveVideoContentFeatures 重新生成一个视频的内容特征，在发布和修改描述后调用，新视频无需等待后台任务即可查找相似视频
+func saveVideoContentFeatures(tx *sql.Tx, videoID int64) error {
+	if _, err := tx.Exec(`DELETE FROM video_content_features WHERE video_id = $1`, videoID); err != nil {
+		return fmt.Errorf("清除视频内容特征失败: %v", err)
+	}
+	_, err := tx.Exec(`
+		INSERT INTO video_content_features (video_id, feature, weight)
+		`+videoContentFeaturesQuery("v.id = $1")+`
+		ON CONFLICT (video_id, feature) DO NOTHING
+	`, videoID)
+	if err != nil {
+		return fmt.Errorf("保存视频内容特征失败: %v", err)
+	}
+	return nil
+}
+
+// RebuildVideoContentFeaturesFromDB 重新生成所有视频的内容特征，用于补齐导入的数据，返回特征数
+func RebuildVideoContentFeaturesFromDB() (int64, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM video_content_features`); err != nil {
+		return 0, fmt.Errorf("清除视频内容特征失败: %v", err)
+	}
+	result, err := tx.Exec(`
+		INSERT INTO video_content_features (video_id, feature, weight)
+		` + videoContentFeaturesQuery("true") + `
+		ON CONFLICT (video_id, feature) DO NOTHING
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("保存视频内容特征失败: %v", err)
+	}
+	count, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return count, nil
+}
+
+// GetSimilarContentVideosFromDB 获取内容相似的视频：按共同特征的权重之和排序，相同时新发布的在前。
+// 只依赖视频本身的话题、音乐、作者和标签，没有互动数据的新视频也能使用
+func GetSimilarContentVideosFromDB(awemeID, viewerID string, limit int) ([]Video, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	videoID, err := getVisibleVideoID(config.DB, awemeID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + videoListColumns + `
+		FROM (
+			SELECT o.video_id, SUM(s.weight) AS score
+			FROM video_content_features s
+			JOIN video_content_features o ON o.feature = s.feature AND o.video_id <> s.video_id
+			WHERE s.video_id = $1
+			GROUP BY o.video_id
+		) m
+		JOIN videos v ON v.id = m.video_id
+		` + videoListJoins + `
+		WHERE ` + videoVisibleCondition("$2") + `
+		ORDER BY m.score DESC, COALESCE(v.create_time, 0) DESC, v.id DESC
+		LIMIT $3
+	`
+	return queryVideoList(query, videoID, viewerID, limit)
+}
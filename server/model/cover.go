@@ -0,0 +1,142 @@
+package model
+
+import (
+	"fmt"
+	"klik/server/config"
+
+	"github.com/lib/pq"
+)
+
+// CoverVariant 自定义封面的一种尺寸
+type CoverVariant struct {
+	Name     string
+	MaxWidth int
+}
+
+// CoverVariants 自定义封面生成的尺寸，从大到小
+var CoverVariants = []CoverVariant{
+	{Name: "large", MaxWidth: 1080},
+	{Name: "medium", MaxWidth: 720},
+	{Name: "small", MaxWidth: 360},
+}
+
+// CoverFile 已生成的封面文件
+type CoverFile struct {
+	Variant string
+	URL     string
+	Width   int
+	Height  int
+}
+
+// SetVideoCoverFromDB 用自定义封面的各尺寸文件替换视频封面，并清理不再引用的旧封面文件
+func SetVideoCoverFromDB(awemeID, userID string, files []CoverFile) ([]Cover, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVideoNotDeleted(tx, videoID); err != nil {
+		return nil, err
+	}
+
+	var oldURLs []string
+	err = tx.QueryRow(`
+		SELECT COALESCE(array_agg(url), '{}') FROM video_covers WHERE video_id = $1 AND url IS NOT NULL
+	`, videoID).Scan(pq.Array(&oldURLs))
+	if err != nil {
+		return nil, fmt.Errorf("查询视频封面失败: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM video_covers WHERE video_id = $1`, videoID); err != nil {
+		return nil, fmt.Errorf("清除视频封面失败: %v", err)
+	}
+
+	// 按从大到小的顺序写入，只取第一行封面的查询得到最大尺寸
+	covers := make([]Cover, 0, len(files))
+	for _, file := range files {
+		cover := Cover{
+			URI:     mediaURI(file.URL),
+			URLList: []string{file.URL},
+			Width:   file.Width,
+			Height:  file.Height,
+		}
+		_, err := tx.Exec(`
+			INSERT INTO video_covers (video_id, uri, url, width, height, variant, is_custom)
+			VALUES ($1, $2, $3, $4, $5, $6, true)
+		`, videoID, cover.URI, file.URL, file.Width, file.Height, file.Variant)
+		if err != nil {
+			return nil, fmt.Errorf("保存视频封面失败: %v", err)
+		}
+		covers = append(covers, cover)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	removeUnreferencedMedia(oldURLs)
+	return covers, nil
+}
+
+// fillVideoCovers 填充视频列表的全部封面尺寸，封面 URLList 按从大到小包含每个尺寸的地址
+func fillVideoCovers(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, COALESCE(vc.uri, ''), COALESCE(vc.url, ''), COALESCE(vc.width, 0),
+		       COALESCE(vc.height, 0), COALESCE(vc.is_custom, false)
+		FROM video_covers vc
+		JOIN videos v ON vc.video_id = v.id
+		WHERE v.aweme_id = ANY($1)
+		ORDER BY vc.width DESC, vc.id
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频封面失败: %v", err)
+	}
+	defer rows.Close()
+
+	variants := make(map[string][]Cover)
+	custom := make(map[string]bool)
+	for rows.Next() {
+		var awemeID, url string
+		var cover Cover
+		var isCustom bool
+		if err := rows.Scan(&awemeID, &cover.URI, &url, &cover.Width, &cover.Height, &isCustom); err != nil {
+			return fmt.Errorf("解析视频封面失败: %v", err)
+		}
+		cover.URLList = []string{url}
+		variants[awemeID] = append(variants[awemeID], cover)
+		custom[awemeID] = custom[awemeID] || isCustom
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频封面时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		list := variants[videos[i].AwemeID]
+		if len(list) == 0 {
+			videos[i].VideoInfo.CoverVariants = []Cover{}
+			continue
+		}
+
+		cover := Cover{URI: list[0].URI, Width: list[0].Width, Height: list[0].Height}
+		for _, variant := range list {
+			cover.URLList = append(cover.URLList, variant.URLList...)
+		}
+		videos[i].VideoInfo.Cover = cover
+		videos[i].VideoInfo.CoverVariants = list
+		videos[i].VideoInfo.UseStaticCover = custom[videos[i].AwemeID]
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"klik/server/config"
+	"math"
+	"strings"
+	"sync"
+)
+
+// 每页条数限制
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 50
+)
+
+// ErrInvalidCursor 分页游标无效、被篡改或不属于当前列表
+var ErrInvalidCursor = errors.New("分页游标无效")
+
+// CursorParams 游标分页参数，第一页不传 cursor，之后传上一页返回的 next_cursor
+type CursorParams struct {
+	Cursor   string `form:"cursor" json:"cursor"`
+	PageSize int    `form:"pageSize" json:"pageSize"`
+}
+
+// Limit 每页条数，未传或超出范围时使用默认值和上限
+func (p CursorParams) Limit() int {
+	if p.PageSize <= 0 {
+		return DefaultPageSize
+	}
+	if p.PageSize > MaxPageSize {
+		return MaxPageSize
+	}
+	return p.PageSize
+}
+
+// pageKey 键集分页的位置：排序值和记录ID，列表按 (Key, ID) 倒序排列
+type pageKey struct {
+	Key int64 `json:"k"`
+	ID  int64 `json:"i"`
+}
+
+// pageCursor 解码后的游标。Scope 标识所属列表（含用户等参数），防止游标被用于其他列表；
+// Snapshot 为排序结果依赖的快照时间，只有推荐等实时计算的列表使用；
+// Filter 为第一页确定的筛选条件（如同城页的城市），翻页时沿用
+type pageCursor struct {
+	Scope    string `json:"s"`
+	Snapshot int64  `json:"t,omitempty"`
+	Filter   string `json:"f,omitempty"`
+	pageKey
+}
+
+// firstPageKey 第一页的位置，比任何记录都大
+var firstPageKey = pageKey{Key: math.MaxInt64, ID: math.MaxInt64}
+
+var (
+	cursorSecretOnce sync.Once
+	cursorSecret     []byte
+)
+
+// getCursorSecret 游标签名密钥，未配置时使用进程启动后随机生成的密钥，重启后旧游标失效（启动时已打印警告）
+func getCursorSecret() []byte {
+	cursorSecretOnce.Do(func() {
+		if secret := config.AppConfig.Server.CursorSecret; secret != "" {
+			cursorSecret = []byte(secret)
+			return
+		}
+		cursorSecret = make([]byte, 32)
+		rand.Read(cursorSecret)
+	})
+	return cursorSecret
+}
+
+// signCursor 计算游标内容的签名
+func signCursor(payload []byte) []byte {
+	mac := hmac.New(sha256.New, getCursorSecret())
+	mac.Write(payload)
+	return mac.Sum(nil)[:16]
+}
+
+// encodeCursor 将游标编码为签名后的不透明字符串
+func encodeCursor(cursor pageCursor) string {
+	payload, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signCursor(payload))
+}
+
+// decodeCursor 校验并解码游标，token 为空表示第一页
+func decodeCursor(token, scope string) (pageCursor, error) {
+	if token == "" {
+		return pageCursor{Scope: scope, pageKey: firstPageKey}, nil
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	payload, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+	signature, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+	if err1 != nil || err2 != nil || !hmac.Equal(signature, signCursor(payload)) {
+		return pageCursor{}, ErrInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.Scope != scope {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// cutPage 截取一页结果。查询时多取一条用于判断是否还有下一页，keys 与 items 一一对应；
+// 还有下一页时返回指向本页最后一条的游标，否则返回空字符串
+func cutPage[T any](items []T, keys []pageKey, limit int, cursor pageCursor) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	next := cursor
+	next.pageKey = keys[limit-1]
+	return items[:limit], encodeCursor(next)
+}
+
+// timestampPageKey 将时间字段转换为分页排序值（微秒），用于按时间排序的列表
+func timestampPageKey(column string) string {
+	return `COALESCE((EXTRACT(EPOCH FROM ` + column + `) * 1000000)::bigint, 0)`
+}
+
+// keyedRow 包装查询行，扫描时在原有字段之后依次读取分页排序值和ID，
+// 用于复用已有的行解析函数
+type keyedRow struct {
+	row interface {
+		Scan(dest ...interface{}) error
+	}
+	key *pageKey
+}
+
+// Scan 扫描行数据及分页位置
+func (r keyedRow) Scan(dest ...interface{}) error {
+	return r.row.Scan(append(dest, &r.key.Key, &r.key.ID)...)
+}
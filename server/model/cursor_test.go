@@ -0,0 +1,110 @@
+package model
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCursorParamsLimit(t *testing.T) {
+	tests := []struct {
+		pageSize int
+		want     int
+	}{
+		{0, DefaultPageSize},
+		{-1, DefaultPageSize},
+		{20, 20},
+		{MaxPageSize, MaxPageSize},
+		{MaxPageSize + 1, MaxPageSize},
+	}
+	for _, tt := range tests {
+		if got := (CursorParams{PageSize: tt.pageSize}).Limit(); got != tt.want {
+			t.Errorf("Limit() with pageSize %d = %d, want %d", tt.pageSize, got, tt.want)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []pageCursor{
+		{Scope: "video:long", pageKey: pageKey{Key: 1700000000, ID: 42}},
+		{Scope: "recommend:u1", Snapshot: 1700000000, pageKey: pageKey{Key: 10, ID: 7}},
+		{Scope: "nearby:u1", Snapshot: 1700000000, Filter: "杭州", pageKey: pageKey{Key: 3, ID: 9}},
+	}
+	for _, cursor := range tests {
+		got, err := decodeCursor(encodeCursor(cursor), cursor.Scope)
+		if err != nil {
+			t.Errorf("decodeCursor(%+v) error: %v", cursor, err)
+			continue
+		}
+		if got != cursor {
+			t.Errorf("decodeCursor = %+v, want %+v", got, cursor)
+		}
+	}
+}
+
+func TestDecodeCursorFirstPage(t *testing.T) {
+	got, err := decodeCursor("", "video:long")
+	if err != nil {
+		t.Fatalf("decodeCursor(\"\") error: %v", err)
+	}
+	if got.Scope != "video:long" || got.pageKey != firstPageKey {
+		t.Errorf("decodeCursor(\"\") = %+v, want first page of video:long", got)
+	}
+}
+
+func TestDecodeCursorRejectsInvalid(t *testing.T) {
+	token := encodeCursor(pageCursor{Scope: "following:u1", pageKey: pageKey{Key: 100, ID: 5}})
+	parts := strings.Split(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"following:u1","k":999,"i":5}`))
+
+	tests := []struct {
+		name  string
+		token string
+		scope string
+	}{
+		{"other scope", token, "following:u2"},
+		{"forged payload", forged + "." + parts[1], "following:u1"},
+		{"forged signature", parts[0] + "." + base64.RawURLEncoding.EncodeToString(make([]byte, 16)), "following:u1"},
+		{"missing signature", parts[0], "following:u1"},
+		{"extra part", token + ".x", "following:u1"},
+		{"not base64", "!!!.???", "following:u1"},
+		{"garbage", "abc", "following:u1"},
+	}
+	for _, tt := range tests {
+		if _, err := decodeCursor(tt.token, tt.scope); err != ErrInvalidCursor {
+			t.Errorf("%s: decodeCursor error = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
+
+func TestCutPage(t *testing.T) {
+	cursor := pageCursor{Scope: "test"}
+	items := []string{"a", "b", "c"}
+	keys := []pageKey{{Key: 3, ID: 3}, {Key: 2, ID: 2}, {Key: 1, ID: 1}}
+
+	tests := []struct {
+		limit    int
+		wantLen  int
+		wantNext *pageKey
+	}{
+		{limit: 3, wantLen: 3},
+		{limit: 5, wantLen: 3},
+		{limit: 2, wantLen: 2, wantNext: &keys[1]},
+	}
+	for _, tt := range tests {
+		page, next := cutPage(items, keys, tt.limit, cursor)
+		if len(page) != tt.wantLen {
+			t.Errorf("cutPage limit %d returned %d items, want %d", tt.limit, len(page), tt.wantLen)
+		}
+		if tt.wantNext == nil {
+			if next != "" {
+				t.Errorf("cutPage limit %d returned next cursor, want none", tt.limit)
+			}
+			continue
+		}
+		got, err := decodeCursor(next, cursor.Scope)
+		if err != nil || got.pageKey != *tt.wantNext {
+			t.Errorf("cutPage limit %d next = %+v (%v), want key %+v", tt.limit, got.pageKey, err, *tt.wantNext)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 弹幕位置
+const (
+	DanmakuPositionScroll = 0 // 从右向左滚动
+	DanmakuPositionTop    = 1 // 顶部固定
+	DanmakuPositionBottom = 2 // 底部固定
+)
+
+// 弹幕字号
+const (
+	DanmakuSizeSmall  = 18
+	DanmakuSizeNormal = 25
+	DanmakuSizeLarge  = 36
+)
+
+// 弹幕限制
+const (
+	MaxDanmakuLength   = 100
+	DanmakuColorWhite  = 0xFFFFFF
+	MaxDanmakuRangeMs  = 10 * 60 * 1000 // 单次最多读取 10 分钟内的弹幕
+	MaxDanmakuPerRange = 3000           // 单次最多返回的弹幕数
+)
+
+// ErrInvalidDanmaku 弹幕参数无效
+var ErrInvalidDanmaku = errors.New("弹幕参数无效")
+
+// Danmaku 弹幕
+type Danmaku struct {
+	DanmakuID  string `json:"danmaku_id"`
+	AwemeID    string `json:"aweme_id"`
+	UserID     string `json:"user_id"`
+	Text       string `json:"text"`
+	OffsetMs   int    `json:"offset_ms"` // 出现的播放时间（毫秒）
+	Color      int    `json:"color"`     // RGB 颜色
+	Position   int    `json:"position"`
+	Size       int    `json:"size"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// DanmakuCreateParams 发送弹幕参数，样式字段为空时使用默认值
+type DanmakuCreateParams struct {
+	AwemeID  string `json:"aweme_id" binding:"required"`
+	Text     string `json:"text" binding:"required"`
+	OffsetMs int    `json:"offset_ms"`
+	Color    *int   `json:"color"`
+	Position int    `json:"position"`
+	Size     int    `json:"size"`
+}
+
+// validateDanmaku 校验弹幕内容和样式，并填充默认值
+func validateDanmaku(params *DanmakuCreateParams) (color int, err error) {
+	params.Text = strings.TrimSpace(params.Text)
+	if params.Text == "" || utf8.RuneCountInString(params.Text) > MaxDanmakuLength {
+		return 0, fmt.Errorf("%w: 内容不能为空且不超过 %d 个字符", ErrInvalidDanmaku, MaxDanmakuLength)
+	}
+	if params.OffsetMs < 0 {
+		return 0, fmt.Errorf("%w: 播放时间无效", ErrInvalidDanmaku)
+	}
+
+	color = DanmakuColorWhite
+	if params.Color != nil {
+		color = *params.Color
+	}
+	if color < 0 || color > DanmakuColorWhite {
+		return 0, fmt.Errorf("%w: 颜色无效", ErrInvalidDanmaku)
+	}
+
+	switch params.Position {
+	case DanmakuPositionScroll, DanmakuPositionTop, DanmakuPositionBottom:
+	default:
+		return 0, fmt.Errorf("%w: 位置无效", ErrInvalidDanmaku)
+	}
+
+	switch params.Size {
+	case 0:
+		params.Size = DanmakuSizeNormal
+	case DanmakuSizeSmall, DanmakuSizeNormal, DanmakuSizeLarge:
+	default:
+		return 0, fmt.Errorf("%w: 字号无效", ErrInvalidDanmaku)
+	}
+	return color, nil
+}
+
+// CreateDanmakuFromDB 发送弹幕，只能发送到自己能看到的视频且播放时间不超过视频时长
+func CreateDanmakuFromDB(userID string, params DanmakuCreateParams) (Danmaku, error) {
+	if config.DB == nil {
+		return Danmaku{}, fmt.Errorf("数据库未初始化")
+	}
+	color, err := validateDanmaku(&params)
+	if err != nil {
+		return Danmaku{}, err
+	}
+
+	videoID, err := getVisibleVideoID(config.DB, params.AwemeID, userID)
+	if err != nil {
+		return Danmaku{}, err
+	}
+
+	var duration int // 毫秒，与 OffsetMs 一致
+	err = config.DB.QueryRow(`SELECT COALESCE(duration, 0) FROM videos WHERE id = $1`, videoID).Scan(&duration)
+	if err != nil {
+		return Danmaku{}, fmt.Errorf("查询视频失败: %v", err)
+	}
+	if duration > 0 && params.OffsetMs > duration {
+		return Danmaku{}, fmt.Errorf("%w: 播放时间超过视频时长", ErrInvalidDanmaku)
+	}
+
+	danmaku := Danmaku{
+		DanmakuID:  newAwemeID(),
+		AwemeID:    params.AwemeID,
+		UserID:     userID,
+		Text:       params.Text,
+		OffsetMs:   params.OffsetMs,
+		Color:      color,
+		Position:   params.Position,
+		Size:       params.Size,
+		CreateTime: time.Now().Unix(),
+	}
+	_, err = config.DB.Exec(`
+		INSERT INTO danmaku (danmaku_id, video_id, user_id, content, offset_ms, color, position, font_size, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, to_timestamp($9))
+	`, danmaku.DanmakuID, videoID, userID, danmaku.Text, danmaku.OffsetMs, danmaku.Color,
+		danmaku.Position, danmaku.Size, danmaku.CreateTime)
+	if err != nil {
+		return Danmaku{}, fmt.Errorf("保存弹幕失败: %v", err)
+	}
+	return danmaku, nil
+}
+
+// GetDanmakuFromDB 获取视频播放时间在 [fromMs, toMs) 内的弹幕，按出现时间排序
+func GetDanmakuFromDB(viewerID, awemeID string, fromMs, toMs int) ([]Danmaku, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	if fromMs < 0 || toMs <= fromMs {
+		return nil, fmt.Errorf("%w: 时间范围无效", ErrInvalidDanmaku)
+	}
+	if toMs-fromMs > MaxDanmakuRangeMs {
+		toMs = fromMs + MaxDanmakuRangeMs
+	}
+
+	videoID, err := getVisibleVideoID(config.DB, awemeID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT danmaku_id, COALESCE(user_id, ''), content, offset_ms, COALESCE(color, $5),
+		       COALESCE(position, 0), COALESCE(font_size, $6), created_at
+		FROM danmaku
+		WHERE video_id = $1 AND offset_ms >= $2 AND offset_ms < $3
+		ORDER BY offset_ms, id
+		LIMIT $4
+	`, videoID, fromMs, toMs, MaxDanmakuPerRange, DanmakuColorWhite, DanmakuSizeNormal)
+	if err != nil {
+		return nil, fmt.Errorf("查询弹幕失败: %v", err)
+	}
+	defer rows.Close()
+
+	list := []Danmaku{}
+	for rows.Next() {
+		danmaku := Danmaku{AwemeID: awemeID}
+		var createdAt time.Time
+		err := rows.Scan(&danmaku.DanmakuID, &danmaku.UserID, &danmaku.Text, &danmaku.OffsetMs,
+			&danmaku.Color, &danmaku.Position, &danmaku.Size, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("解析弹幕失败: %v", err)
+		}
+		danmaku.CreateTime = createdAt.Unix()
+		list = append(list, danmaku)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询弹幕时发生错误: %v", err)
+	}
+	return list, nil
+}
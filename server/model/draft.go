@@ -0,0 +1,540 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 草稿类型
+const (
+	DraftTypeVideo = "video" // 视频草稿，发布到 videos 表
+	DraftTypePost  = "post"  // 图文草稿，发布到 posts 表
+)
+
+var (
+	// ErrDraftNotFound 草稿不存在或不属于当前用户
+	ErrDraftNotFound = errors.New("草稿不存在")
+	// ErrInvalidDraft 草稿内容无效
+	ErrInvalidDraft = errors.New("草稿内容无效")
+	// ErrDraftIncomplete 草稿缺少发布所需的媒体
+	ErrDraftIncomplete = errors.New("草稿缺少视频或图片，无法发布")
+	// ErrMusicNotFound 音乐不存在
+	ErrMusicNotFound = errors.New("音乐不存在")
+)
+
+// DraftMedia 草稿引用的已上传视频
+type DraftMedia struct {
+	URI      string `json:"uri"`
+	URL      string `json:"url"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	DataSize int64  `json:"data_size"`
+	FileHash string `json:"file_hash"`
+	Duration int    `json:"duration"`
+}
+
+// Draft 草稿
+type Draft struct {
+	DraftID       string     `json:"draft_id"`
+	DraftType     string     `json:"draft_type"`
+	Desc          string     `json:"desc"`
+	VideoType     string     `json:"video_type"`
+	Media         DraftMedia `json:"media"`
+	ImageURLs     []string   `json:"image_urls"`
+	MusicID       int64      `json:"music_id"`
+	CoverURL      string     `json:"cover_url"`
+	PrivateStatus int        `json:"private_status"`
+	CreateTime    int64      `json:"create_time"`
+	UpdateTime    int64      `json:"update_time"`
+}
+
+// DraftSaveParams 创建或修改草稿参数，为空的字段保持不变
+type DraftSaveParams struct {
+	DraftType     *string     `json:"draft_type"` // 仅创建时有效
+	Desc          *string     `json:"desc"`
+	VideoType     *string     `json:"video_type"`
+	Media         *DraftMedia `json:"media"`
+	ImageURLs     *[]string   `json:"image_urls"`
+	MusicID       *int64      `json:"music_id"` // 传 0 清除配乐
+	CoverURL      *string     `json:"cover_url"`
+	PrivateStatus *int        `json:"private_status"`
+}
+
+// DraftPublishResult 草稿发布结果
+type DraftPublishResult struct {
+	AwemeID   string `json:"aweme_id"`
+	DraftType string `json:"draft_type"`
+}
+
+// UserMediaDir 用户上传文件在数据目录下的子目录，草稿只能引用该目录中的文件
+func UserMediaDir(userID string) string {
+	return path.Join("upload", filepath.Base(userID))
+}
+
+// isUserMediaURL 判断地址是否为该用户上传的本地文件
+func isUserMediaURL(url, userID string) bool {
+	localPath := localMediaPath(url)
+	if localPath == "" {
+		return false
+	}
+	dir := filepath.Join(config.DataPath, UserMediaDir(userID)) + string(filepath.Separator)
+	return strings.HasPrefix(localPath, dir)
+}
+
+// draftRetention 草稿保留时长
+func draftRetention() time.Duration {
+	days := config.AppConfig.Draft.RetentionDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// applyDraftParams 将参数合并到草稿
+func applyDraftParams(draft *Draft, params DraftSaveParams) {
+	if params.Desc != nil {
+		draft.Desc = *params.Desc
+	}
+	if params.VideoType != nil {
+		draft.VideoType = *params.VideoType
+	}
+	if params.Media != nil {
+		draft.Media = *params.Media
+	}
+	if params.ImageURLs != nil {
+		draft.ImageURLs = *params.ImageURLs
+	}
+	if params.MusicID != nil {
+		draft.MusicID = *params.MusicID
+	}
+	if params.CoverURL != nil {
+		draft.CoverURL = *params.CoverURL
+	}
+	if params.PrivateStatus != nil {
+		draft.PrivateStatus = *params.PrivateStatus
+	}
+}
+
+// validateDraft 校验草稿内容，引用的文件必须是作者本人上传的
+func validateDraft(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, draft *Draft, userID string) error {
+	switch draft.DraftType {
+	case DraftTypeVideo:
+		if draft.VideoType == "" {
+			draft.VideoType = "recommend-video"
+		}
+		if !uploadVideoTypes[draft.VideoType] {
+			return ErrInvalidVideoType
+		}
+		if len(draft.ImageURLs) > 0 {
+			return fmt.Errorf("%w: 视频草稿不能包含图片", ErrInvalidDraft)
+		}
+	case DraftTypePost:
+		if draft.Media.URL != "" {
+			return fmt.Errorf("%w: 图文草稿不能包含视频", ErrInvalidDraft)
+		}
+		if len(draft.ImageURLs) > MaxPhotoImages {
+			return fmt.Errorf("%w: 图片最多 %d 张", ErrInvalidDraft, MaxPhotoImages)
+		}
+	default:
+		return fmt.Errorf("%w: 草稿类型只能是 video 或 post", ErrInvalidDraft)
+	}
+
+	if err := validateVideoPrivacy(&draft.PrivateStatus, nil); err != nil {
+		return err
+	}
+
+	urls := append([]string{draft.Media.URL, draft.CoverURL}, draft.ImageURLs...)
+	for _, url := range urls {
+		if url != "" && !isUserMediaURL(url, userID) {
+			return fmt.Errorf("%w: 文件 %s 不是本人上传的", ErrInvalidDraft, url)
+		}
+	}
+	if draft.Media.URL != "" {
+		draft.Media.URI = mediaURI(draft.Media.URL)
+	}
+
+	if draft.MusicID != 0 {
+		var exists bool
+		err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM music WHERE id = $1)`, draft.MusicID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("查询音乐失败: %v", err)
+		}
+		if !exists {
+			return ErrMusicNotFound
+		}
+	}
+	return nil
+}
+
+// draftColumns 查询草稿的字段，与 scanDraft 对应
+const draftColumns = `
+	draft_id, draft_type, COALESCE(description, ''), COALESCE(video_type, ''),
+	COALESCE(media_uri, ''), COALESCE(media_url, ''), COALESCE(width, 0), COALESCE(height, 0),
+	COALESCE(data_size, 0), COALESCE(file_hash, ''), COALESCE(duration, 0), COALESCE(image_urls, '{}'),
+	COALESCE(music_id, 0), COALESCE(cover_url, ''), COALESCE(private_status, 0), created_at, updated_at`
+
+// scanDraft 解析草稿行
+func scanDraft(row interface {
+	Scan(dest ...interface{}) error
+}) (Draft, error) {
+	var draft Draft
+	var createdAt, updatedAt time.Time
+	err := row.Scan(
+		&draft.DraftID, &draft.DraftType, &draft.Desc, &draft.VideoType,
+		&draft.Media.URI, &draft.Media.URL, &draft.Media.Width, &draft.Media.Height,
+		&draft.Media.DataSize, &draft.Media.FileHash, &draft.Media.Duration, pq.Array(&draft.ImageURLs),
+		&draft.MusicID, &draft.CoverURL, &draft.PrivateStatus, &createdAt, &updatedAt,
+	)
+	if err != nil {
+		return draft, err
+	}
+	draft.CreateTime = createdAt.Unix()
+	draft.UpdateTime = updatedAt.Unix()
+	return draft, nil
+}
+
+// draftFileURLs 草稿引用的所有文件地址
+func draftFileURLs(draft Draft) []string {
+	urls := []string{}
+	for _, url := range append([]string{draft.Media.URL, draft.CoverURL}, draft.ImageURLs...) {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// CreateDraftFromDB 创建草稿
+func CreateDraftFromDB(userID string, params DraftSaveParams) (Draft, error) {
+	if config.DB == nil {
+		return Draft{}, fmt.Errorf("数据库未初始化")
+	}
+
+	draft := Draft{DraftID: newAwemeID()}
+	if params.DraftType != nil {
+		draft.DraftType = *params.DraftType
+	}
+	applyDraftParams(&draft, params)
+	if err := validateDraft(config.DB, &draft, userID); err != nil {
+		return Draft{}, err
+	}
+
+	row := config.DB.QueryRow(`
+		INSERT INTO drafts (draft_id, author_user_id, draft_type, description, video_type,
+		                    media_uri, media_url, width, height, data_size, file_hash, duration,
+		                    image_urls, music_id, cover_url, private_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, 0), $15, $16)
+		RETURNING `+draftColumns,
+		draft.DraftID, userID, draft.DraftType, draft.Desc, draft.VideoType,
+		draft.Media.URI, draft.Media.URL, draft.Media.Width, draft.Media.Height, draft.Media.DataSize,
+		draft.Media.FileHash, draft.Media.Duration, pq.Array(draft.ImageURLs), draft.MusicID,
+		draft.CoverURL, draft.PrivateStatus)
+	created, err := scanDraft(row)
+	if err != nil {
+		return Draft{}, fmt.Errorf("保存草稿失败: %v", err)
+	}
+	return created, nil
+}
+
+// getDraftForUpdate 获取作者本人的草稿并加锁
+func getDraftForUpdate(tx *sql.Tx, draftID, userID string) (Draft, error) {
+	row := tx.QueryRow(`
+		SELECT `+draftColumns+`
+		FROM drafts WHERE draft_id = $1 AND author_user_id = $2
+		FOR UPDATE
+	`, draftID, userID)
+	draft, err := scanDraft(row)
+	if err == sql.ErrNoRows {
+		return Draft{}, ErrDraftNotFound
+	}
+	if err != nil {
+		return Draft{}, fmt.Errorf("查询草稿失败: %v", err)
+	}
+	return draft, nil
+}
+
+// GetDraftFromDB 获取作者本人的草稿
+func GetDraftFromDB(draftID, userID string) (Draft, error) {
+	if config.DB == nil {
+		return Draft{}, fmt.Errorf("数据库未初始化")
+	}
+
+	row := config.DB.QueryRow(`
+		SELECT `+draftColumns+`
+		FROM drafts WHERE draft_id = $1 AND author_user_id = $2
+	`, draftID, userID)
+	draft, err := scanDraft(row)
+	if err == sql.ErrNoRows {
+		return Draft{}, ErrDraftNotFound
+	}
+	if err != nil {
+		return Draft{}, fmt.Errorf("查询草稿失败: %v", err)
+	}
+	return draft, nil
+}
+
+// GetDraftsFromDB 获取用户草稿列表，最近修改的在前，返回下一页游标
+func GetDraftsFromDB(userID, token string, limit int) ([]Draft, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "draft:"+userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	updatedAt := timestampPageKey("updated_at")
+	rows, err := config.DB.Query(`
+		SELECT `+draftColumns+`, `+updatedAt+`, id
+		FROM drafts WHERE author_user_id = $1 AND (`+updatedAt+`, id) < ($2, $3)
+		ORDER BY `+updatedAt+` DESC, id DESC
+		LIMIT $4
+	`, userID, cursor.Key, cursor.ID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询草稿失败: %v", err)
+	}
+	defer rows.Close()
+
+	drafts := []Draft{}
+	var keys []pageKey
+	for rows.Next() {
+		var key pageKey
+		draft, err := scanDraft(keyedRow{rows, &key})
+		if err != nil {
+			return nil, "", fmt.Errorf("解析草稿失败: %v", err)
+		}
+		drafts = append(drafts, draft)
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("查询草稿时发生错误: %v", err)
+	}
+
+	drafts, next := cutPage(drafts, keys, limit, cursor)
+	return drafts, next, nil
+}
+
+// GetDraftCountFromDB 获取用户草稿总数
+func GetDraftCountFromDB(userID string) (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	var count int
+	err := config.DB.QueryRow(`SELECT COUNT(*) FROM drafts WHERE author_user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("查询草稿总数失败: %v", err)
+	}
+
+	return count, nil
+}
+
+// UpdateDraftFromDB 修改草稿，被替换掉的文件在提交后删除
+func UpdateDraftFromDB(draftID, userID string, params DraftSaveParams) (Draft, error) {
+	if config.DB == nil {
+		return Draft{}, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return Draft{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	draft, err := getDraftForUpdate(tx, draftID, userID)
+	if err != nil {
+		return Draft{}, err
+	}
+	oldFiles := draftFileURLs(draft)
+
+	applyDraftParams(&draft, params)
+	if err := validateDraft(tx, &draft, userID); err != nil {
+		return Draft{}, err
+	}
+
+	row := tx.QueryRow(`
+		UPDATE drafts SET description = $2, video_type = $3, media_uri = $4, media_url = $5,
+		                  width = $6, height = $7, data_size = $8, file_hash = $9, duration = $10,
+		                  image_urls = $11, music_id = NULLIF($12, 0), cover_url = $13,
+		                  private_status = $14, updated_at = CURRENT_TIMESTAMP
+		WHERE draft_id = $1
+		RETURNING `+draftColumns,
+		draftID, draft.Desc, draft.VideoType, draft.Media.URI, draft.Media.URL,
+		draft.Media.Width, draft.Media.Height, draft.Media.DataSize, draft.Media.FileHash, draft.Media.Duration,
+		pq.Array(draft.ImageURLs), draft.MusicID, draft.CoverURL, draft.PrivateStatus)
+	updated, err := scanDraft(row)
+	if err != nil {
+		return Draft{}, fmt.Errorf("修改草稿失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Draft{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	removeUnreferencedMedia(oldFiles)
+	return updated, nil
+}
+
+// DeleteDraftFromDB 删除草稿及其不再被引用的文件
+func DeleteDraftFromDB(draftID, userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	draft, err := getDraftForUpdate(tx, draftID, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM drafts WHERE draft_id = $1`, draftID); err != nil {
+		return fmt.Errorf("删除草稿失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	removeUnreferencedMedia(draftFileURLs(draft))
+	return nil
+}
+
+// PublishDraftFromDB 发布草稿，在同一事务中写入视频或图文并删除草稿。
+// publishTime 仅对视频草稿有效，非 0 时定时发布
+func PublishDraftFromDB(draftID, userID string, publishTime int64) (DraftPublishResult, error) {
+	if config.DB == nil {
+		return DraftPublishResult{}, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return DraftPublishResult{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	draft, err := getDraftForUpdate(tx, draftID, userID)
+	if err != nil {
+		return DraftPublishResult{}, err
+	}
+	// 草稿保存后文件或音乐可能已变化，发布前重新校验
+	if err := validateDraft(tx, &draft, userID); err != nil {
+		return DraftPublishResult{}, err
+	}
+
+	var awemeID string
+	switch draft.DraftType {
+	case DraftTypeVideo:
+		if draft.Media.URL == "" {
+			return DraftPublishResult{}, ErrDraftIncomplete
+		}
+		awemeID, err = insertVideo(tx, VideoCreateParams{
+			AuthorUserID:  userID,
+			Desc:          draft.Desc,
+			VideoType:     draft.VideoType,
+			Duration:      draft.Media.Duration,
+			PrivateStatus: draft.PrivateStatus,
+			PublishTime:   publishTime,
+			PlayURI:       draft.Media.URI,
+			PlayURL:       draft.Media.URL,
+			Width:         draft.Media.Width,
+			Height:        draft.Media.Height,
+			DataSize:      draft.Media.DataSize,
+			FileHash:      draft.Media.FileHash,
+			MusicID:       draft.MusicID,
+			CoverURL:      draft.CoverURL,
+		})
+	case DraftTypePost:
+		if len(draft.ImageURLs) == 0 {
+			return DraftPublishResult{}, ErrDraftIncomplete
+		}
+		awemeID, err = insertPost(tx, PostCreateParams{
+			AuthorUserID:  userID,
+			Desc:          draft.Desc,
+			ImageURLs:     draft.ImageURLs,
+			CoverURL:      draft.CoverURL,
+			MusicID:       draft.MusicID,
+			PrivateStatus: draft.PrivateStatus,
+		})
+	}
+	if err != nil {
+		return DraftPublishResult{}, err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM drafts WHERE draft_id = $1`, draftID); err != nil {
+		return DraftPublishResult{}, fmt.Errorf("删除草稿失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return DraftPublishResult{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return DraftPublishResult{AwemeID: awemeID, DraftType: draft.DraftType}, nil
+}
+
+// PurgeExpiredDraftsFromDB 删除超过保留期未修改的草稿，返回删除数量
+func PurgeExpiredDraftsFromDB() (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	rows, err := config.DB.Query(`
+		DELETE FROM drafts WHERE updated_at < $1
+		RETURNING `+draftColumns,
+		time.Now().Add(-draftRetention()))
+	if err != nil {
+		return 0, fmt.Errorf("清理过期草稿失败: %v", err)
+	}
+	var files []string
+	purged := 0
+	for rows.Next() {
+		draft, err := scanDraft(rows)
+		if err != nil {
+			rows.Close()
+			return purged, fmt.Errorf("解析过期草稿失败: %v", err)
+		}
+		files = append(files, draftFileURLs(draft)...)
+		purged++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("清理过期草稿时发生错误: %v", err)
+	}
+
+	removeUnreferencedMedia(files)
+	return purged, nil
+}
+
+// removeUnreferencedMedia 删除不再被任何视频、图文、草稿或音乐引用的本地文件
+func removeUnreferencedMedia(urls []string) {
+	for _, url := range urls {
+		var referenced bool
+		err := config.DB.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM video_play_addresses WHERE url = $1)
+			    OR EXISTS (SELECT 1 FROM video_covers WHERE url = $1)
+			    OR EXISTS (SELECT 1 FROM video_subtitles WHERE url = $1)
+			    OR EXISTS (SELECT 1 FROM video_images WHERE url = $1)
+			    OR EXISTS (SELECT 1 FROM post_images WHERE image_url = $1)
+			    OR EXISTS (SELECT 1 FROM post_covers WHERE url = $1)
+			    OR EXISTS (SELECT 1 FROM drafts WHERE media_url = $1 OR cover_url = $1 OR $1 = ANY (image_urls))
+			    OR EXISTS (SELECT 1 FROM music WHERE play_url = $1 OR cover_url = $1)
+		`, url).Scan(&referenced)
+		if err != nil || referenced {
+			continue
+		}
+		removeLocalMediaFile(url)
+	}
+}
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"fmt"
+	"hash/fnv"
+	"klik/server/config"
+
+	"github.com/lib/pq"
+)
+
+// ExperimentFeedRanking 推荐列表排序实验，各分组的 strategy 对应推荐策略
+const ExperimentFeedRanking = "feed_ranking"
+
+// ExperimentControl 对照组名称，未分到任何实验组的用户属于对照组
+const ExperimentControl = "control"
+
+// experimentBuckets 流量分桶数，配置中的百分比即桶数
+const experimentBuckets = 100
+
+// ExperimentAssignment 用户在实验中的分组
+type ExperimentAssignment struct {
+	Experiment string `json:"experiment"` // 实验未配置时为空
+	Variant    string `json:"variant"`
+	Strategy   string `json:"strategy"`
+	Bucket     int    `json:"bucket"`
+}
+
+// ExperimentVariantExposure 实验分组的曝光统计
+type ExperimentVariantExposure struct {
+	Variant     string `json:"variant"`
+	Strategy    string `json:"strategy"`
+	Percent     int    `json:"percent"`
+	Users       int    `json:"users"`       // 曝光过的用户数
+	Impressions int    `json:"impressions"` // 曝光的视频次数
+}
+
+// ExperimentExposure 实验的曝光统计
+type ExperimentExposure struct {
+	Experiment string                      `json:"experiment"`
+	Variants   []ExperimentVariantExposure `json:"variants"`
+}
+
+// experimentBucket 按实验名和用户 uid 哈希分桶，同一用户在同一实验中的分桶固定，不同实验之间相互独立
+func experimentBucket(experiment, userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(experiment + ":" + userID))
+	return int(h.Sum32() % experimentBuckets)
+}
+
+// ValidateExperimentStrategies 校验配置中各实验分组的 strategy 都是已实现的推荐策略，
+// 启动时调用，避免分组名义上的策略与实际使用的不一致
+func ValidateExperimentStrategies() error {
+	for _, experiment := range config.AppConfig.Experiments {
+		for _, variant := range experiment.Variants {
+			if _, ok := recommendStrategies[variant.Strategy]; !ok {
+				return fmt.Errorf("实验 %s 的分组 %s 使用了未知的策略 %q", experiment.Name, variant.Name, variant.Strategy)
+			}
+		}
+	}
+	return nil
+}
+
+// findExperiment 查找配置中的实验
+func findExperiment(name string) (config.ExperimentConfig, bool) {
+	for _, experiment := range config.AppConfig.Experiments {
+		if experiment.Name == name {
+			return experiment, true
+		}
+	}
+	return config.ExperimentConfig{}, false
+}
+
+// AssignExperiment 获取用户在实验中的分组：按分桶依次落入各分组的流量区间，超出所有区间的为对照组。
+// 实验未配置时返回空实验名和默认策略
+func AssignExperiment(name, userID string) ExperimentAssignment {
+	experiment, ok := findExperiment(name)
+	if !ok {
+		return ExperimentAssignment{Variant: ExperimentControl, Strategy: RecommendStrategyDefault}
+	}
+
+	bucket := experimentBucket(name, userID)
+	assignment := ExperimentAssignment{
+		Experiment: name,
+		Variant:    ExperimentControl,
+		Strategy:   RecommendStrategyDefault,
+		Bucket:     bucket,
+	}
+	upper := 0
+	for _, variant := range experiment.Variants {
+		if variant.Percent <= 0 {
+			continue
+		}
+		upper += variant.Percent
+		if bucket < upper {
+			assignment.Variant = variant.Name
+			assignment.Strategy = variant.Strategy
+			break
+		}
+	}
+	return assignment
+}
+
+// LogExperimentImpressionsFromDB 记录一次列表请求返回的视频曝光及用户所在的分组，实验未配置时不记录
+func LogExperimentImpressionsFromDB(assignment ExperimentAssignment, userID, feed string, videos []Video) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if assignment.Experiment == "" || len(videos) == 0 {
+		return nil
+	}
+
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+	_, err := config.DB.Exec(`
+		INSERT INTO experiment_impressions (experiment, variant, user_id, feed, aweme_id)
+		SELECT $1, $2, $3, $4, unnest($5::text[])
+	`, assignment.Experiment, assignment.Variant, userID, feed, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("记录实验曝光失败: %v", err)
+	}
+	return nil
+}
+
+// GetExperimentExposuresFromDB 获取各实验分组的曝光用户数和曝光次数。
+// 配置中的分组和对照组都会列出，已从配置中移除的分组如有曝光记录也会列出
+func GetExperimentExposuresFromDB() ([]ExperimentExposure, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	var exposures []ExperimentExposure
+	index := make(map[string]int)
+	variantIndex := make(map[string]map[string]int)
+	addVariant := func(experiment string, variant ExperimentVariantExposure) *ExperimentVariantExposure {
+		i, ok := index[experiment]
+		if !ok {
+			i = len(exposures)
+			index[experiment] = i
+			variantIndex[experiment] = make(map[string]int)
+			exposures = append(exposures, ExperimentExposure{Experiment: experiment, Variants: []ExperimentVariantExposure{}})
+		}
+		j, ok := variantIndex[experiment][variant.Variant]
+		if !ok {
+			j = len(exposures[i].Variants)
+			variantIndex[experiment][variant.Variant] = j
+			exposures[i].Variants = append(exposures[i].Variants, variant)
+		}
+		return &exposures[i].Variants[j]
+	}
+
+	for _, experiment := range config.AppConfig.Experiments {
+		control := experimentBuckets
+		for _, variant := range experiment.Variants {
+			addVariant(experiment.Name, ExperimentVariantExposure{Variant: variant.Name, Strategy: variant.Strategy, Percent: variant.Percent})
+			control -= variant.Percent
+		}
+		addVariant(experiment.Name, ExperimentVariantExposure{Variant: ExperimentControl, Strategy: RecommendStrategyDefault, Percent: control})
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT experiment, variant, COUNT(DISTINCT user_id), COUNT(*)
+		FROM experiment_impressions
+		GROUP BY experiment, variant
+		ORDER BY experiment, variant
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询实验曝光失败: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var experiment, variant string
+		var users, impressions int
+		if err := rows.Scan(&experiment, &variant, &users, &impressions); err != nil {
+			return nil, fmt.Errorf("解析实验曝光失败: %v", err)
+		}
+		exposure := addVariant(experiment, ExperimentVariantExposure{Variant: variant})
+		exposure.Users = users
+		exposure.Impressions = impressions
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询实验曝光时发生错误: %v", err)
+	}
+	return exposures, nil
+}
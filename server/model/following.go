@@ -0,0 +1,89 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+	"time"
+)
+
+// feedFollowing 关注页，对应 user_feed_visits.feed
+const feedFollowing = "following"
+
+// getFeedVisitTime 获取用户上次访问信息流的时间（Unix 秒），从未访问过时返回 0
+func getFeedVisitTime(userID, feed string) (int64, error) {
+	var lastVisit int64
+	err := config.DB.QueryRow(`
+		SELECT last_visit_time FROM user_feed_visits WHERE user_id = $1 AND feed = $2
+	`, userID, feed).Scan(&lastVisit)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询访问记录失败: %v", err)
+	}
+	return lastVisit, nil
+}
+
+// recordFeedVisit 记录用户访问信息流的时间
+func recordFeedVisit(userID, feed string, visitTime int64) error {
+	_, err := config.DB.Exec(`
+		INSERT INTO user_feed_visits (user_id, feed, last_visit_time)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, feed) DO UPDATE SET last_visit_time = EXCLUDED.last_visit_time
+	`, userID, feed, visitTime)
+	if err != nil {
+		return fmt.Errorf("记录访问时间失败: %v", err)
+	}
+	return nil
+}
+
+// markUnseenVideos 标记上次访问之后发布的视频
+func markUnseenVideos(videos []Video, lastVisit int64) {
+	for i := range videos {
+		videos[i].IsUnseen = videos[i].CreateTime > lastVisit
+	}
+}
+
+// GetFollowingVideosFromDB 获取观看者关注的作者发布的视频，最新发布的在前。
+// 获取第一页时记录本次访问时间，上次访问之后发布的视频标记为未看过；
+// 上次访问时间保存在游标中，翻页期间标记保持一致
+func GetFollowingVideosFromDB(viewerID, token string, limit int) ([]Video, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "following:"+viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().Unix()
+	if token == "" {
+		if cursor.Snapshot, err = getFeedVisitTime(viewerID, feedFollowing); err != nil {
+			return nil, "", err
+		}
+	}
+
+	query := `
+		SELECT ` + videoListColumns + `, COALESCE(v.create_time, 0), v.id
+		FROM videos v
+		JOIN user_follows f ON f.following_id = v.author_user_id AND f.follower_id = $4
+		` + videoListJoins + `
+		WHERE ` + videoVisibleCondition("$4") + `
+		  AND ` + authorNotBlockedCondition("$4") + `
+		  AND (COALESCE(v.create_time, 0), v.id) < ($1, $2)
+		ORDER BY COALESCE(v.create_time, 0) DESC, v.id DESC
+		LIMIT $3
+	`
+	videos, next, err := queryVideoPage(cursor, limit, query, viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	markUnseenVideos(videos, cursor.Snapshot)
+
+	if token == "" {
+		if err := recordFeedVisit(viewerID, feedFollowing, now); err != nil {
+			return nil, "", err
+		}
+	}
+	return videos, next, nil
+}
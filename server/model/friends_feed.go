@@ -0,0 +1,179 @@
+package model
+
+import (
+	"fmt"
+	"klik/server/config"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// 好友动态类型，对应 FriendActivity.Action
+const (
+	FriendActionPost    = "post"    // 发布
+	FriendActionLike    = "like"    // 赞过
+	FriendActionComment = "comment" // 评论过
+)
+
+// friendActionText 好友动态在推荐理由中的文案
+var friendActionText = map[string]string{
+	FriendActionPost:    "发布",
+	FriendActionLike:    "赞过",
+	FriendActionComment: "评论过",
+}
+
+// friendLabelMaxNames 推荐理由中每种动态最多列出的好友数
+const friendLabelMaxNames = 2
+
+// FriendActivity 好友对视频的动态
+type FriendActivity struct {
+	Action   string `json:"action"` // post、like、comment
+	UID      string `json:"uid"`
+	Nickname string `json:"nickname"`
+	Time     int64  `json:"time"` // Unix 秒
+}
+
+// viewerFriendsQuery 返回观看者所有好友 uid 的子查询，好友关系按双向处理，与观看者有拉黑关系的好友除外
+func viewerFriendsQuery(viewerParam string) string {
+	return `
+		SELECT ub.uid FROM user_friends uf
+		JOIN users ua ON ua.id = uf.user_id
+		JOIN users ub ON ub.id = uf.friend_id
+		WHERE ua.uid = ` + viewerParam + ` AND ` + userNotBlockedCondition("ub.uid", viewerParam) + `
+		UNION
+		SELECT ua.uid FROM user_friends uf
+		JOIN users ua ON ua.id = uf.user_id
+		JOIN users ub ON ub.id = uf.friend_id
+		WHERE ub.uid = ` + viewerParam + ` AND ` + userNotBlockedCondition("ua.uid", viewerParam)
+}
+
+// friendActivitiesQuery 返回好友动态的子查询，每行为 (video_id, action, user_id, key)，
+// key 为动态发生时间（微秒），与分页排序值一致
+func friendActivitiesQuery(viewerParam string) string {
+	friends := viewerFriendsQuery(viewerParam)
+	return `
+		SELECT v.id AS video_id, '` + FriendActionPost + `' AS action, v.author_user_id AS user_id,
+		       COALESCE(v.create_time, 0) * 1000000 AS key
+		FROM videos v
+		WHERE v.author_user_id IN (` + friends + `)
+		UNION ALL
+		SELECT ulv.video_id, '` + FriendActionLike + `', ulv.commenter_id, ` + timestampPageKey("ulv.created_at") + `
+		FROM user_like_videos ulv
+		WHERE ulv.commenter_id IN (` + friends + `)
+		UNION ALL
+		SELECT c.video_id, '` + FriendActionComment + `', c.commenter_id, ` + timestampPageKey("c.created_at") + `
+		FROM comments c
+		WHERE c.video_id IS NOT NULL AND c.commenter_id IN (` + friends + `)`
+}
+
+// GetFriendsVideosFromDB 获取好友发布、赞过或评论过的视频，按最近一次好友动态倒序排列。
+// 同一视频只出现一次，friend_activities 列出相关好友的动态，friend_label 为推荐理由
+func GetFriendsVideosFromDB(viewerID, token string, limit int) ([]Video, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "friends:"+viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `
+		SELECT ` + videoListColumns + `, fa.key, v.id
+		FROM (
+			SELECT a.video_id, MAX(a.key) AS key
+			FROM (` + friendActivitiesQuery("$4") + `) a
+			GROUP BY a.video_id
+		) fa
+		JOIN videos v ON v.id = fa.video_id
+		` + videoListJoins + `
+		WHERE v.author_user_id IS DISTINCT FROM $4
+		  AND ` + videoVisibleCondition("$4") + `
+		  AND ` + authorNotBlockedCondition("$4") + `
+		  AND (fa.key, v.id) < ($1, $2)
+		ORDER BY fa.key DESC, v.id DESC
+		LIMIT $3
+	`
+	videos, next, err := queryVideoPage(cursor, limit, query, viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := fillFriendActivities(videos, viewerID); err != nil {
+		return nil, "", err
+	}
+	return videos, next, nil
+}
+
+// fillFriendActivities 填充视频的好友动态和推荐理由，同一好友的同类动态只保留最近一次
+func fillFriendActivities(videos []Video, viewerID string) error {
+	if len(videos) == 0 {
+		return nil
+	}
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, a.action, u.uid, COALESCE(u.nickname, ''), a.key
+		FROM (`+friendActivitiesQuery("$2")+`) a
+		JOIN videos v ON v.id = a.video_id
+		JOIN users u ON u.uid = a.user_id
+		WHERE v.aweme_id = ANY($1)
+		ORDER BY a.key DESC
+	`, pq.Array(awemeIDs), viewerID)
+	if err != nil {
+		return fmt.Errorf("查询好友动态失败: %v", err)
+	}
+	defer rows.Close()
+
+	activities := make(map[string][]FriendActivity)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var awemeID string
+		var activity FriendActivity
+		var key int64
+		if err := rows.Scan(&awemeID, &activity.Action, &activity.UID, &activity.Nickname, &key); err != nil {
+			return fmt.Errorf("解析好友动态失败: %v", err)
+		}
+		dedupKey := awemeID + "|" + activity.Action + "|" + activity.UID
+		if seen[dedupKey] {
+			continue
+		}
+		seen[dedupKey] = true
+		activity.Time = key / 1000000
+		activities[awemeID] = append(activities[awemeID], activity)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询好友动态时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		videos[i].FriendActivities = activities[videos[i].AwemeID]
+		videos[i].FriendLabel = friendLabel(videos[i].FriendActivities)
+	}
+	return nil
+}
+
+// friendLabel 生成推荐理由，如 “张三 发布 · 李四、王五 等 3 位好友赞过”，按动态最近发生的顺序排列
+func friendLabel(activities []FriendActivity) string {
+	var actions []string
+	names := make(map[string][]string)
+	for _, activity := range activities {
+		if _, ok := names[activity.Action]; !ok {
+			actions = append(actions, activity.Action)
+		}
+		names[activity.Action] = append(names[activity.Action], activity.Nickname)
+	}
+
+	parts := make([]string, 0, len(actions))
+	for _, action := range actions {
+		list := names[action]
+		if len(list) > friendLabelMaxNames {
+			parts = append(parts, fmt.Sprintf("%s 等 %d 位好友%s",
+				strings.Join(list[:friendLabelMaxNames], "、"), len(list), friendActionText[action]))
+			continue
+		}
+		parts = append(parts, strings.Join(list, "、")+" "+friendActionText[action])
+	}
+	return strings.Join(parts, " · ")
+}
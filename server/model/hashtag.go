@@ -0,0 +1,247 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// TextExtraTypeHashtag 文本额外信息中的话题类型
+const TextExtraTypeHashtag = 1
+
+// MaxHashtagLength 话题名最大字符数
+const MaxHashtagLength = 50
+
+// ErrHashtagNotFound 话题不存在
+var ErrHashtagNotFound = errors.New("话题不存在")
+
+// Hashtag 话题详情
+type Hashtag struct {
+	HashtagID   string `json:"hashtag_id"`
+	HashtagName string `json:"hashtag_name"`
+	ViewCount   int64  `json:"view_count"` // 话题下视频的总播放量
+	VideoCount  int    `json:"video_count"`
+}
+
+// textSpan 文本中解析出的 #话题 或 @用户，Start/End 为字符偏移，End 不包含
+type textSpan struct {
+	Name  string
+	Start int
+	End   int
+}
+
+// isHashtagRune 判断字符能否作为话题名的一部分
+func isHashtagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// parseTextSpans 解析以 marks 中任一字符开头的片段，片段名由 isNameRune 允许的字符组成，最长 maxLen 个字符
+func parseTextSpans(text, marks string, isNameRune func(rune) bool, maxLen int) []textSpan {
+	runes := []rune(text)
+	spans := []textSpan{}
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(marks, runes[i]) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isNameRune(runes[end]) && end-i-1 < maxLen {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+		spans = append(spans, textSpan{Name: string(runes[i+1 : end]), Start: i, End: end})
+		i = end - 1
+	}
+	return spans
+}
+
+// parseHashtags 从描述中解析 #话题，话题名遇到空白、标点或下一个 # 结束
+func parseHashtags(text string) []textSpan {
+	return parseTextSpans(text, "#＃", isHashtagRune, MaxHashtagLength)
+}
+
+// saveVideoHashtags 重新解析并保存视频描述中的话题
+func saveVideoHashtags(tx *sql.Tx, videoID int64, desc string) error {
+	if _, err := tx.Exec(`DELETE FROM video_hashtags WHERE video_id = $1`, videoID); err != nil {
+		return fmt.Errorf("清除视频话题失败: %v", err)
+	}
+
+	for _, span := range parseHashtags(desc) {
+		var hashtagID int64
+		err := tx.QueryRow(`
+			INSERT INTO hashtags (name) VALUES ($1)
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		`, span.Name).Scan(&hashtagID)
+		if err != nil {
+			return fmt.Errorf("保存话题失败: %v", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO video_hashtags (video_id, hashtag_id, start_index, end_index)
+			VALUES ($1, $2, $3, $4)
+		`, videoID, hashtagID, span.Start, span.End)
+		if err != nil {
+			return fmt.Errorf("保存视频话题失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// fillVideoHashtags 填充视频列表的话题信息
+func fillVideoHashtags(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, vh.start_index, vh.end_index, h.id, h.name
+		FROM video_hashtags vh
+		JOIN videos v ON vh.video_id = v.id
+		JOIN hashtags h ON vh.hashtag_id = h.id
+		WHERE v.aweme_id = ANY($1)
+		ORDER BY vh.start_index
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频话题失败: %v", err)
+	}
+	defer rows.Close()
+
+	extras := make(map[string][]TextExtra)
+	for rows.Next() {
+		var awemeID, name string
+		var start, end int
+		var hashtagID int64
+		if err := rows.Scan(&awemeID, &start, &end, &hashtagID, &name); err != nil {
+			return fmt.Errorf("解析视频话题失败: %v", err)
+		}
+		extras[awemeID] = append(extras[awemeID], TextExtra{
+			Start:       start,
+			End:         end,
+			Type:        TextExtraTypeHashtag,
+			HashtagName: name,
+			HashtagID:   strconv.FormatInt(hashtagID, 10),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频话题时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		if extra, ok := extras[videos[i].AwemeID]; ok {
+			videos[i].TextExtra = append(videos[i].TextExtra, extra...)
+		}
+	}
+	return nil
+}
+
+// GetHashtagFromDB 获取话题详情，播放量和视频数只统计当前用户可见的视频
+func GetHashtagFromDB(viewerID, name string) (Hashtag, error) {
+	if config.DB == nil {
+		return Hashtag{}, fmt.Errorf("数据库未初始化")
+	}
+
+	var hashtag Hashtag
+	var hashtagID int64
+	err := config.DB.QueryRow(`SELECT id, name FROM hashtags WHERE name = $1`, normalizeHashtag(name)).
+		Scan(&hashtagID, &hashtag.HashtagName)
+	if err == sql.ErrNoRows {
+		return Hashtag{}, ErrHashtagNotFound
+	}
+	if err != nil {
+		return Hashtag{}, fmt.Errorf("查询话题失败: %v", err)
+	}
+	hashtag.HashtagID = strconv.FormatInt(hashtagID, 10)
+
+	err = config.DB.QueryRow(`
+		SELECT COALESCE(SUM(COALESCE(vs.play_count, 0)), 0), COUNT(*)
+		FROM videos v
+		LEFT JOIN video_statistics vs ON v.id = vs.video_id
+		WHERE EXISTS (SELECT 1 FROM video_hashtags vh WHERE vh.video_id = v.id AND vh.hashtag_id = $1)
+		  AND `+videoVisibleCondition("$2"), hashtagID, viewerID).Scan(&hashtag.ViewCount, &hashtag.VideoCount)
+	if err != nil {
+		return Hashtag{}, fmt.Errorf("查询话题统计失败: %v", err)
+	}
+
+	return hashtag, nil
+}
+
+// getHashtagsByID 批量获取话题详情，统计规则与 GetHashtagFromDB 相同，不存在的话题不包含在结果中
+func getHashtagsByID(hashtagIDs []int64, viewerID string) (map[int64]Hashtag, error) {
+	hashtags := make(map[int64]Hashtag)
+	if len(hashtagIDs) == 0 {
+		return hashtags, nil
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT h.id, h.name, s.view_count, s.video_count
+		FROM hashtags h
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(SUM(COALESCE(vs.play_count, 0)), 0) AS view_count, COUNT(*) AS video_count
+			FROM videos v
+			LEFT JOIN video_statistics vs ON v.id = vs.video_id
+			WHERE EXISTS (SELECT 1 FROM video_hashtags vh WHERE vh.video_id = v.id AND vh.hashtag_id = h.id)
+			  AND `+videoVisibleCondition("$2")+`
+		) s
+		WHERE h.id = ANY($1)
+	`, pq.Array(hashtagIDs), viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("查询话题失败: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hashtagID int64
+		var hashtag Hashtag
+		if err := rows.Scan(&hashtagID, &hashtag.HashtagName, &hashtag.ViewCount, &hashtag.VideoCount); err != nil {
+			return nil, fmt.Errorf("解析话题数据失败: %v", err)
+		}
+		hashtag.HashtagID = strconv.FormatInt(hashtagID, 10)
+		hashtags[hashtagID] = hashtag
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询话题时发生错误: %v", err)
+	}
+	return hashtags, nil
+}
+
+// GetHashtagVideosFromDB 获取话题下的视频列表，最新发布的在前
+func GetHashtagVideosFromDB(viewerID, name, token string, limit int) ([]Video, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	name = normalizeHashtag(name)
+	cursor, err := decodeCursor(token, "hashtag:"+name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `
+		SELECT ` + videoListColumns + `, COALESCE(v.create_time, 0), v.id
+		FROM videos v
+		` + videoListJoins + `
+		WHERE EXISTS (
+			SELECT 1 FROM video_hashtags vh
+			JOIN hashtags h ON vh.hashtag_id = h.id
+			WHERE vh.video_id = v.id AND h.name = $4
+		) AND ` + videoVisibleCondition("$5") + `
+		  AND (COALESCE(v.create_time, 0), v.id) < ($1, $2)
+		ORDER BY COALESCE(v.create_time, 0) DESC, v.id DESC
+		LIMIT $3
+	`
+	return queryVideoPage(cursor, limit, query, name, viewerID)
+}
+
+// normalizeHashtag 去掉话题名前的 # 和首尾空白
+func normalizeHashtag(name string) string {
+	name = strings.TrimSpace(name)
+	return strings.TrimLeft(name, "#＃")
+}
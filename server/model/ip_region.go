@@ -0,0 +1,120 @@
+package model
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"klik/server/config"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ipRegion IP 地址库中的一个网段及其所在地
+type ipRegion struct {
+	Network string
+	Location
+}
+
+// parseIPRegions 解析 CSV 格式的 IP 地址库，每行依次为网段、国家、省份、城市。
+// 网段为 CIDR（如 1.2.3.0/24），单个 IP 按 /32 或 /128 处理；# 开头的行和 network 表头会被跳过
+func parseIPRegions(r io.Reader) ([]ipRegion, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	var regions []ipRegion
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 IP 地址库失败: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		network := strings.TrimSpace(record[0])
+		if len(regions) == 0 && strings.EqualFold(network, "network") {
+			continue
+		}
+
+		if !strings.Contains(network, "/") {
+			ip := net.ParseIP(network)
+			if ip == nil {
+				return nil, fmt.Errorf("IP 地址库第 %d 行网段无效: %s", line, network)
+			}
+			if ip.To4() != nil {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, fmt.Errorf("IP 地址库第 %d 行网段无效: %s", line, network)
+		}
+
+		regions = append(regions, ipRegion{
+			Network: ipNet.String(),
+			Location: Location{
+				Country:  strings.TrimSpace(record[1]),
+				Province: strings.TrimSpace(record[2]),
+				City:     strings.TrimSpace(record[3]),
+			},
+		})
+	}
+	return regions, nil
+}
+
+// ImportIPRegionsFromFile 从 CSV 文件导入 IP 地址库，替换 ip_regions 表中的全部数据，返回导入的网段数
+func ImportIPRegionsFromFile(path string) (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("打开 IP 地址库失败: %v", err)
+	}
+	defer file.Close()
+
+	regions, err := parseIPRegions(file)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM ip_regions`); err != nil {
+		return 0, fmt.Errorf("清空 IP 归属地失败: %v", err)
+	}
+	stmt, err := tx.Prepare(pq.CopyIn("ip_regions", "network", "country", "province", "city"))
+	if err != nil {
+		return 0, fmt.Errorf("准备导入 IP 归属地失败: %v", err)
+	}
+	for _, region := range regions {
+		if _, err := stmt.Exec(region.Network, region.Country, region.Province, region.City); err != nil {
+			stmt.Close()
+			return 0, fmt.Errorf("导入 IP 归属地失败: %v", err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return 0, fmt.Errorf("导入 IP 归属地失败: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return 0, fmt.Errorf("导入 IP 归属地失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return len(regions), nil
+}
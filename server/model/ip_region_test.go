@@ -0,0 +1,49 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIPRegions(t *testing.T) {
+	input := `# 注释
+network,country,province,city
+1.2.3.0/24, 中国, 浙江省, 杭州市
+1.2.3.77/24,中国,浙江省,杭州市
+8.8.8.8,美国,,
+2001:db8::/32,中国,上海市,上海市
+::1,中国,浙江省,杭州市
+`
+	want := []ipRegion{
+		{Network: "1.2.3.0/24", Location: Location{Country: "中国", Province: "浙江省", City: "杭州市"}},
+		{Network: "1.2.3.0/24", Location: Location{Country: "中国", Province: "浙江省", City: "杭州市"}},
+		{Network: "8.8.8.8/32", Location: Location{Country: "美国"}},
+		{Network: "2001:db8::/32", Location: Location{Country: "中国", Province: "上海市", City: "上海市"}},
+		{Network: "::1/128", Location: Location{Country: "中国", Province: "浙江省", City: "杭州市"}},
+	}
+	got, err := parseIPRegions(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseIPRegions error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseIPRegions = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseIPRegionsRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"bad network", "1.2.3.0/24,中国,浙江省,杭州市\nnot-an-ip,中国,浙江省,杭州市\n"},
+		{"bad mask", "1.2.3.0/33,中国,浙江省,杭州市\n"},
+		{"missing fields", "1.2.3.0/24,中国\n"},
+		{"header not first", "1.2.3.0/24,中国,浙江省,杭州市\nnetwork,country,province,city\n"},
+	}
+	for _, tt := range tests {
+		if _, err := parseIPRegions(strings.NewReader(tt.input)); err == nil {
+			t.Errorf("%s: parseIPRegions succeeded, want error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+	"sort"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+// TextExtraTypeUser 文本额外信息中的 @用户 类型
+const TextExtraTypeUser = 0
+
+// MaxMentionLength @ 后用户名最大字符数
+const MaxMentionLength = 30
+
+// NoticeTypeMention 被 @ 提及的通知类型
+const NoticeTypeMention = "mention"
+
+// mention 解析并确认可提及的用户
+type mention struct {
+	UserID string
+	Start  int
+	End    int
+}
+
+// isMentionRune 判断字符能否作为 @ 后昵称或抖音号的一部分
+func isMentionRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
+
+// parseMentions 从文本中解析 @昵称 或 @抖音号
+func parseMentions(text string) []textSpan {
+	return parseTextSpans(text, "@＠", isMentionRune, MaxMentionLength)
+}
+
+// resolveMentions 将文本中的 @ 解析为用户 uid。
+// 优先匹配抖音号，其次匹配昵称（同名时优先作者关注的人）；
+// 被提及者拉黑了作者或关闭了 @ 权限时丢弃该提及
+func resolveMentions(tx *sql.Tx, authorID, text string) ([]mention, error) {
+	mentions := []mention{}
+	for _, span := range parseMentions(text) {
+		var userID string
+		var allowed bool
+		err := tx.QueryRow(`
+			SELECT u.uid,
+			       NOT EXISTS (SELECT 1 FROM user_blocks b WHERE b.blocker_id = u.uid AND b.blocked_id = $2)
+			       AND COALESCE((SELECT ps.allow_mention FROM user_privacy_settings ps WHERE ps.user_id = u.uid), true)
+			FROM users u
+			WHERE u.unique_id = $1 OR u.nickname = $1
+			ORDER BY (u.unique_id = $1) DESC,
+			         EXISTS (SELECT 1 FROM user_follows f WHERE f.follower_id = $2 AND f.following_id = u.uid) DESC,
+			         u.id
+			LIMIT 1
+		`, span.Name, authorID).Scan(&userID, &allowed)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("查询提及用户失败: %v", err)
+		}
+		if !allowed && userID != authorID {
+			continue
+		}
+		mentions = append(mentions, mention{UserID: userID, Start: span.Start, End: span.End})
+	}
+	return mentions, nil
+}
+
+// saveVideoMentions 重新解析并保存视频描述中的 @用户，并通知新提及且能看到该视频的用户
+func saveVideoMentions(tx *sql.Tx, videoID int64, authorID, awemeID, desc string) error {
+	var previous []string
+	err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(DISTINCT user_id), '{}') FROM video_mentions WHERE video_id = $1
+	`, videoID).Scan(pq.Array(&previous))
+	if err != nil {
+		return fmt.Errorf("查询视频提及失败: %v", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM video_mentions WHERE video_id = $1`, videoID); err != nil {
+		return fmt.Errorf("清除视频提及失败: %v", err)
+	}
+
+	mentions, err := resolveMentions(tx, authorID, desc)
+	if err != nil {
+		return err
+	}
+	for _, m := range mentions {
+		_, err := tx.Exec(`
+			INSERT INTO video_mentions (video_id, user_id, start_index, end_index)
+			VALUES ($1, $2, $3, $4)
+		`, videoID, m.UserID, m.Start, m.End)
+		if err != nil {
+			return fmt.Errorf("保存视频提及失败: %v", err)
+		}
+	}
+
+	return notifyVideoMentions(tx, videoID, authorID, awemeID, desc, previous)
+}
+
+// notifyVideoMentions 通知视频描述中提及的用户，跳过作者本人、exclude 中的用户和看不到该视频的用户。
+// 定时发布的视频在发布前对所有人不可见，发布时再调用本函数
+func notifyVideoMentions(tx *sql.Tx, videoID int64, authorID, awemeID, desc string, exclude []string) error {
+	if exclude == nil {
+		exclude = []string{}
+	}
+	_, err := tx.Exec(`
+		INSERT INTO notifications (user_id, sender_id, notice_type, aweme_id, content)
+		SELECT DISTINCT vm.user_id, $2, $3, $4, $5
+		FROM video_mentions vm
+		JOIN videos v ON vm.video_id = v.id
+		WHERE vm.video_id = $1 AND vm.user_id <> $2 AND NOT (vm.user_id = ANY($6))
+		  AND `+videoVisibleCondition("vm.user_id"),
+		videoID, authorID, NoticeTypeMention, awemeID, "在视频中提到了你："+desc, pq.Array(exclude))
+	if err != nil {
+		return fmt.Errorf("发送提及通知失败: %v", err)
+	}
+	return nil
+}
+
+// savePostMentions 保存图文中的 @用户，并通知被提及且能看到该图文的用户
+func savePostMentions(tx *sql.Tx, postID int64, authorID, awemeID, text string) error {
+	mentions, err := resolveMentions(tx, authorID, text)
+	if err != nil {
+		return err
+	}
+	for _, m := range mentions {
+		_, err := tx.Exec(`
+			INSERT INTO post_text_extra (post_id, start_index, end_index, extra_type, user_id)
+			VALUES ($1, $2, $3, $4, $5)
+		`, postID, m.Start, m.End, TextExtraTypeUser, m.UserID)
+		if err != nil {
+			return fmt.Errorf("保存图文提及失败: %v", err)
+		}
+	}
+
+	return notifyPostMentions(tx, postID, authorID, awemeID, text)
+}
+
+// notifyPostMentions 通知图文中提及的用户，跳过作者本人和看不到该图文的用户
+func notifyPostMentions(tx *sql.Tx, postID int64, authorID, awemeID, text string) error {
+	_, err := tx.Exec(`
+		INSERT INTO notifications (user_id, sender_id, notice_type, aweme_id, content)
+		SELECT DISTINCT pte.user_id, $2, $3, $4, $5
+		FROM post_text_extra pte
+		JOIN posts p ON pte.post_id = p.id
+		WHERE pte.post_id = $1 AND pte.extra_type = $6 AND pte.user_id <> $2
+		  AND `+postVisibleCondition("pte.user_id"),
+		postID, authorID, NoticeTypeMention, awemeID, "在图文中提到了你："+text, TextExtraTypeUser)
+	if err != nil {
+		return fmt.Errorf("发送提及通知失败: %v", err)
+	}
+	return nil
+}
+
+// fillVideoMentions 填充视频列表的 @用户 信息
+func fillVideoMentions(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, vm.start_index, vm.end_index, vm.user_id, COALESCE(u.sec_uid, '')
+		FROM video_mentions vm
+		JOIN videos v ON vm.video_id = v.id
+		JOIN users u ON vm.user_id = u.uid
+		WHERE v.aweme_id = ANY($1)
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频提及失败: %v", err)
+	}
+	defer rows.Close()
+
+	extras := make(map[string][]TextExtra)
+	for rows.Next() {
+		var awemeID string
+		var extra TextExtra
+		if err := rows.Scan(&awemeID, &extra.Start, &extra.End, &extra.UserID, &extra.SecUID); err != nil {
+			return fmt.Errorf("解析视频提及失败: %v", err)
+		}
+		extra.Type = TextExtraTypeUser
+		extras[awemeID] = append(extras[awemeID], extra)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频提及时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		if extra, ok := extras[videos[i].AwemeID]; ok {
+			videos[i].TextExtra = append(videos[i].TextExtra, extra...)
+		}
+	}
+	return nil
+}
+
+// sortVideoTextExtra 将话题和 @用户 按在描述中的位置排序，便于客户端逐段渲染
+func sortVideoTextExtra(videos []Video) error {
+	for i := range videos {
+		extras := videos[i].TextExtra
+		sort.SliceStable(extras, func(a, b int) bool {
+			return extras[a].Start < extras[b].Start
+		})
+	}
+	return nil
+}
+
+// saveCommentMentions 保存评论中的 @用户，并通知能看到该视频的被提及用户
+func saveCommentMentions(tx *sql.Tx, commentID, videoID int64, authorID, awemeID, text string) ([]mention, error) {
+	mentions, err := resolveMentions(tx, authorID, text)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mentions {
+		_, err := tx.Exec(`
+			INSERT INTO comment_mentions (comment_id, user_id, start_index, end_index)
+			VALUES ($1, $2, $3, $4)
+		`, commentID, m.UserID, m.Start, m.End)
+		if err != nil {
+			return nil, fmt.Errorf("保存评论提及失败: %v", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO notifications (user_id, sender_id, notice_type, aweme_id, content)
+		SELECT DISTINCT cm.user_id, $3, $4, $5, $6
+		FROM comment_mentions cm
+		JOIN videos v ON v.id = $2
+		WHERE cm.comment_id = $1 AND cm.user_id <> $3
+		  AND `+videoVisibleCondition("cm.user_id"),
+		commentID, videoID, authorID, NoticeTypeMention, awemeID, "在评论中提到了你："+text)
+	if err != nil {
+		return nil, fmt.Errorf("发送提及通知失败: %v", err)
+	}
+	return mentions, nil
+}
+
+// fillCommentMentions 填充评论列表的 @用户 信息，commentIDs 为与 comments 一一对应的评论内部ID
+func fillCommentMentions(comments []Comment, commentIDs []int64) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT cm.comment_id, cm.start_index, cm.end_index, cm.user_id, COALESCE(u.sec_uid, '')
+		FROM comment_mentions cm
+		JOIN users u ON cm.user_id = u.uid
+		WHERE cm.comment_id = ANY($1)
+		ORDER BY cm.start_index
+	`, pq.Array(commentIDs))
+	if err != nil {
+		return fmt.Errorf("查询评论提及失败: %v", err)
+	}
+	defer rows.Close()
+
+	extras := make(map[int64][]TextExtra)
+	for rows.Next() {
+		var commentID int64
+		extra := TextExtra{Type: TextExtraTypeUser}
+		if err := rows.Scan(&commentID, &extra.Start, &extra.End, &extra.UserID, &extra.SecUID); err != nil {
+			return fmt.Errorf("解析评论提及失败: %v", err)
+		}
+		extras[commentID] = append(extras[commentID], extra)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询评论提及时发生错误: %v", err)
+	}
+
+	for i, commentID := range commentIDs {
+		if extra, ok := extras[commentID]; ok {
+			comments[i].TextExtra = append(comments[i].TextExtra, extra...)
+		}
+	}
+	return nil
+}
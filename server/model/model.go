@@ -10,9 +10,10 @@ type Response struct {
 
 // PageResponse 分页响应
 type PageResponse struct {
-	PageNo int         `json:"pageNo,omitempty"`
-	Total  int         `json:"total"`
-	List   interface{} `json:"list"`
+	Total      int         `json:"total,omitempty"` // 总数，只有能准确统计的列表返回
+	List       interface{} `json:"list"`
+	HasMore    bool        `json:"has_more"`
+	NextCursor string      `json:"next_cursor"` // 下一页游标，没有更多时为空
 }
 
 // AvatarInfo 头像信息
@@ -45,8 +46,15 @@ type Video struct {
 	AuthorUserID    string         `json:"author_user_id"`
 	PreventDownload bool           `json:"prevent_download"`
 	LongVideo       interface{}    `json:"long_video"`
+	Series          *VideoSeries   `json:"series"` // 所属合集，不属于合集时为 null
+	Chapters        []Chapter      `json:"chapters"`
 	AwemeControl    AwemeControl   `json:"aweme_control"`
-	Images          interface{}    `json:"images"`
+	AwemeType       int            `json:"aweme_type"` // 0 视频，68 图文
+	Images          []PhotoImage   `json:"images"`     // 图文作品的图片，按顺序展示；视频为 null
+	IsUnseen        bool           `json:"is_unseen,omitempty"` // 关注页中上次访问之后发布的视频
+	FriendActivities []FriendActivity `json:"friend_activities,omitempty"` // 好友页中好友对该视频的动态
+	FriendLabel     string         `json:"friend_label,omitempty"`    // 好友页推荐理由，如 “张三 赞过”
+	RecommendReason *RecommendReason `json:"recommend_reason,omitempty"` // 推荐列表中的推荐理由，如 “因为你喜欢过 X”
 	SuggestWords    SuggestWords   `json:"suggest_words"`
 	Author          Author         `json:"author"`
 }
@@ -58,6 +66,7 @@ type Comment struct {
 	User         User      `json:"user"`
 	CreateTime   int64     `json:"create_time"`
 	DiggCount    int       `json:"digg_count"`
+	TextExtra    []TextExtra `json:"text_extra"`
 	ReplyComment []Comment `json:"reply_comment,omitempty"`
 }
 
@@ -71,6 +80,7 @@ type Post struct {
 	CommentCount int      `json:"comment_count"`
 	ShareCount   int      `json:"share_count"`
 	Images       []string `json:"images,omitempty"`
+	TextExtra    []TextExtra `json:"text_extra"`
 }
 
 // Good 商品
@@ -104,6 +114,8 @@ type MusicInfo struct {
 	ID            int64      `json:"id"`
 	Title         string     `json:"title"`
 	Author        string     `json:"author"`
+	CoverHD       CoverMedia `json:"cover_hd"`
+	CoverLarge    CoverMedia `json:"cover_large"`
 	CoverMedium   CoverMedia `json:"cover_medium"`
 	CoverThumb    CoverMedia `json:"cover_thumb"`
 	PlayURL       PlayMedia  `json:"play_url"`
@@ -140,6 +152,8 @@ type VideoInfo struct {
 	Ratio         string   `json:"ratio"`
 	UseStaticCover bool     `json:"use_static_cover"`
 	Duration      int      `json:"duration"`
+	CoverVariants []Cover  `json:"cover_variants"` // 各尺寸封面，从大到小
+	Subtitles     []SubtitleTrack `json:"subtitles"`
 }
 
 // PlayAddr 播放地址
@@ -196,6 +210,8 @@ type TextExtra struct {
 	Type         int    `json:"type"`
 	HashtagName  string `json:"hashtag_name"`
 	HashtagID    string `json:"hashtag_id"`
+	UserID       string `json:"user_id,omitempty"`
+	SecUID       string `json:"sec_uid,omitempty"`
 	IsCommerce   bool   `json:"is_commerce"`
 	CaptionStart int    `json:"caption_start"`
 	CaptionEnd   int    `json:"caption_end"`
@@ -356,25 +372,6 @@ type ShareQrcodeURL struct {
 	URLList []string `json:"url_list"`
 }
 
-// PageParams 分页参数
-type PageParams struct {
-	PageNo   int `form:"pageNo" json:"pageNo"`
-	PageSize int `form:"pageSize" json:"pageSize"`
-}
-
-// VideoRecommendParams 视频推荐参数
-type VideoRecommendParams struct {
-	Start    int `form:"start" json:"start"`
-	PageSize int `form:"pageSize" json:"pageSize"`
-}
-
-// GetPageRange 获取页面范围
-func GetPageRange(params PageParams) (int, int) {
-	offset := params.PageNo * params.PageSize
-	limit := params.PageNo*params.PageSize + params.PageSize
-	return offset, limit
-}
-
 // User 用户
 type User struct {
 	UID           string      `json:"uid"`
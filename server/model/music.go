@@ -0,0 +1,306 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// originalMusicCoverSize 原声封面使用作者 300x300 头像
+const originalMusicCoverSize = 300
+
+// MaxMusicTitleLength 音乐标题最大字符数
+const MaxMusicTitleLength = 100
+
+var (
+	// ErrMusicConflict 同时选择了原声和已有音乐
+	ErrMusicConflict = errors.New("不能同时使用原声和已有音乐")
+	// ErrInvalidMusic 音乐参数无效
+	ErrInvalidMusic = errors.New("音乐参数无效")
+)
+
+// MusicCreateParams 登记原声参数
+type MusicCreateParams struct {
+	OwnerUserID string
+	Title       string // 为空时使用“@昵称创作的原声”
+	PlayURL     string
+	Duration    int // 时长（秒）
+}
+
+// MusicDetail 音乐详情
+type MusicDetail struct {
+	MusicInfo
+	IDStr           string `json:"id_str"`
+	Album           string `json:"album"`
+	IsRestricted    bool   `json:"is_restricted"`
+	PreventDownload bool   `json:"prevent_download"`
+	OriginalAwemeID string `json:"original_aweme_id"` // 原声作品，没有或不可见时为空
+}
+
+// musicInfoColumns 音乐信息查询字段，与 scanMusicInfo 对应，要求音乐表别名为 m。
+// 使用人数只统计公开可见的视频
+const musicInfoColumns = `
+	m.id, m.title, COALESCE(m.author, ''), COALESCE(m.cover_uri, ''), COALESCE(m.cover_url, ''),
+	COALESCE(m.cover_width, 0), COALESCE(m.cover_height, 0), COALESCE(m.play_url, ''), COALESCE(m.duration, 0), COALESCE(m.owner_id, ''),
+	COALESCE(m.owner_nickname, ''), COALESCE(m.is_original, false)`
+
+// musicUserCountColumn 音乐使用人数字段，要求音乐表别名为 m
+func musicUserCountColumn() string {
+	return `(SELECT COUNT(*) FROM videos v WHERE v.music_id = m.id AND ` + videoPublicCondition() + `)`
+}
+
+// newMusicInfo 根据数据库字段构建音乐信息。音乐只保存一张封面，各尺寸字段返回同一张图片及其实际尺寸，
+// 尺寸未知时为 0
+func newMusicInfo(id int64, title, author string, cover CoverMedia, playURL string, duration, userCount int,
+	ownerID, ownerNickname string, isOriginal bool) MusicInfo {
+	return MusicInfo{
+		ID:          id,
+		Title:       title,
+		Author:      author,
+		CoverHD:     cover,
+		CoverLarge:  cover,
+		CoverMedium: cover,
+		CoverThumb:  cover,
+		PlayURL: PlayMedia{
+			URI:     "",
+			URLList: []string{playURL},
+		},
+		Duration:      duration,
+		UserCount:     userCount,
+		OwnerID:       ownerID,
+		OwnerNickname: ownerNickname,
+		IsOriginal:    isOriginal,
+	}
+}
+
+// scanMusicInfo 解析 musicInfoColumns 与使用人数字段
+func scanMusicInfo(row interface {
+	Scan(dest ...interface{}) error
+}, extra ...interface{}) (MusicInfo, error) {
+	var (
+		id                                int64
+		title, author, coverURI, coverURL string
+		coverWidth, coverHeight           int
+		playURL, ownerID, ownerNickname   string
+		duration, userCount               int
+		isOriginal                        bool
+	)
+	dest := append(extra, &id, &title, &author, &coverURI, &coverURL, &coverWidth, &coverHeight,
+		&playURL, &duration, &ownerID, &ownerNickname, &isOriginal, &userCount)
+	if err := row.Scan(dest...); err != nil {
+		return MusicInfo{}, err
+	}
+	cover := CoverMedia{URI: coverURI, URLList: []string{coverURL}, Width: coverWidth, Height: coverHeight}
+	return newMusicInfo(id, title, author, cover, playURL, duration, userCount,
+		ownerID, ownerNickname, isOriginal), nil
+}
+
+// musicOriginalVideoID 原声作品查询，即音乐上传者最早使用该音乐的视频，musicParam 为音乐ID的占位符
+func musicOriginalVideoID(musicParam string) string {
+	return `(
+		SELECT ov.id FROM videos ov
+		JOIN music om ON ov.music_id = om.id
+		WHERE om.id = ` + musicParam + ` AND ov.author_user_id = om.owner_id
+		ORDER BY ov.create_time, ov.id
+		LIMIT 1
+	)`
+}
+
+// fillVideoMusic 用音乐表的数据填充视频列表的音乐信息（封面、使用人数等）
+func fillVideoMusic(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT mv.aweme_id, `+musicInfoColumns+`, `+musicUserCountColumn()+`
+		FROM videos mv
+		JOIN music m ON mv.music_id = m.id
+		WHERE mv.aweme_id = ANY($1)
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频音乐失败: %v", err)
+	}
+	defer rows.Close()
+
+	musics := make(map[string]MusicInfo)
+	for rows.Next() {
+		var awemeID string
+		music, err := scanMusicInfo(rows, &awemeID)
+		if err != nil {
+			return fmt.Errorf("解析视频音乐失败: %v", err)
+		}
+		musics[awemeID] = music
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频音乐时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		if music, ok := musics[videos[i].AwemeID]; ok {
+			videos[i].Music = music
+		}
+	}
+	return nil
+}
+
+// GetMusicDetailFromDB 获取音乐详情
+func GetMusicDetailFromDB(musicID int64) (MusicDetail, error) {
+	if config.DB == nil {
+		return MusicDetail{}, fmt.Errorf("数据库未初始化")
+	}
+
+	var detail MusicDetail
+	row := config.DB.QueryRow(`
+		SELECT COALESCE(m.id_str, ''), COALESCE(m.album, ''), COALESCE(m.is_restricted, false),
+		       COALESCE(m.prevent_download, false),
+		       COALESCE((SELECT v.aweme_id FROM videos v WHERE v.id = `+musicOriginalVideoID("$1")+` AND `+videoPublicCondition()+`), ''),
+		       `+musicInfoColumns+`, `+musicUserCountColumn()+`
+		FROM music m
+		WHERE m.id = $1
+	`, musicID)
+	music, err := scanMusicInfo(row, &detail.IDStr, &detail.Album, &detail.IsRestricted,
+		&detail.PreventDownload, &detail.OriginalAwemeID)
+	if err == sql.ErrNoRows {
+		return MusicDetail{}, ErrMusicNotFound
+	}
+	if err != nil {
+		return MusicDetail{}, fmt.Errorf("查询音乐详情失败: %v", err)
+	}
+	detail.MusicInfo = music
+	return detail, nil
+}
+
+// musicVideoPageKey 音乐视频列表的排序值：原声作品排在最前，其余按点赞数、再按播放量排序。
+// 三者依次占用 1、31、31 位组合为一个 bigint，musicParam 为音乐ID的占位符
+func musicVideoPageKey(musicParam string) string {
+	return `(
+		((CASE WHEN v.id IS NOT DISTINCT FROM ` + musicOriginalVideoID(musicParam) + ` THEN 1 ELSE 0 END)::bigint << 62)
+		| (LEAST(GREATEST(COALESCE(vs.digg_count, 0), 0), 2147483647)::bigint << 31)
+		| LEAST(GREATEST(COALESCE(vs.play_count, 0), 0), 2147483647)::bigint
+	)`
+}
+
+// GetMusicVideosFromDB 获取使用该音乐的视频，原声作品排在最前，其余按点赞和播放量排序
+func GetMusicVideosFromDB(viewerID string, musicID int64, token string, limit int) ([]Video, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, fmt.Sprintf("music:%d", musicID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	sortKey := musicVideoPageKey("$4")
+	query := `
+		SELECT ` + videoListColumns + `, ` + sortKey + `, v.id
+		FROM videos v
+		` + videoListJoins + `
+		WHERE v.music_id = $4 AND ` + videoVisibleCondition("$5") + `
+		  AND (` + sortKey + `, v.id) < ($1, $2)
+		ORDER BY ` + sortKey + ` DESC, v.id DESC
+		LIMIT $3
+	`
+	return queryVideoPage(cursor, limit, query, musicID, viewerID)
+}
+
+// GetMusicVideoCountFromDB 获取使用该音乐且当前用户可见的视频总数
+func GetMusicVideoCountFromDB(viewerID string, musicID int64) (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	var count int
+	err := config.DB.QueryRow(`
+		SELECT COUNT(*) FROM videos v WHERE v.music_id = $1 AND `+videoVisibleCondition("$2"),
+		musicID, viewerID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("查询音乐视频总数失败: %v", err)
+	}
+	return count, nil
+}
+
+// newMusicID 生成音乐ID
+func newMusicID() int64 {
+	return time.Now().UnixMilli()*1000 + rand.Int63n(1000)
+}
+
+// createOriginalMusic 在事务中将作者的音频登记为原声，封面使用作者头像，返回音乐ID
+func createOriginalMusic(tx *sql.Tx, params MusicCreateParams) (int64, error) {
+	title := strings.TrimSpace(params.Title)
+	if params.PlayURL == "" || params.Duration < 0 || utf8.RuneCountInString(title) > MaxMusicTitleLength {
+		return 0, ErrInvalidMusic
+	}
+
+	var nickname, avatarURI, avatarURL string
+	err := tx.QueryRow(`
+		SELECT COALESCE(nickname, ''), COALESCE(avatar_300x300_uri, ''), COALESCE(avatar_300x300_url, '')
+		FROM users WHERE uid = $1
+	`, params.OwnerUserID).Scan(&nickname, &avatarURI, &avatarURL)
+	if err == sql.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询用户失败: %v", err)
+	}
+	if title == "" {
+		title = "@" + nickname + "创作的原声"
+	}
+
+	musicID := newMusicID()
+	_, err = tx.Exec(`
+		INSERT INTO music (id, id_str, title, author, cover_uri, cover_url, cover_width, cover_height,
+		                   play_url, duration, owner_id, owner_nickname, is_original)
+		VALUES ($1, $2, $3, $4, $5, $6, $10, $10, $7, $8, $9, $4, true)
+	`, musicID, strconv.FormatInt(musicID, 10), title, nickname, avatarURI, avatarURL,
+		params.PlayURL, params.Duration, params.OwnerUserID, originalMusicCoverSize)
+	if err != nil {
+		return 0, fmt.Errorf("保存原声失败: %v", err)
+	}
+	return musicID, nil
+}
+
+// checkMusicExists 校验音乐是否存在
+func checkMusicExists(tx *sql.Tx, musicID int64) error {
+	var exists bool
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM music WHERE id = $1)`, musicID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("查询音乐失败: %v", err)
+	}
+	if !exists {
+		return ErrMusicNotFound
+	}
+	return nil
+}
+
+// CreateOriginalMusicFromDB 将上传的音频登记为原声，其他用户发布时可以选用
+func CreateOriginalMusicFromDB(params MusicCreateParams) (MusicDetail, error) {
+	if config.DB == nil {
+		return MusicDetail{}, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return MusicDetail{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	musicID, err := createOriginalMusic(tx, params)
+	if err != nil {
+		return MusicDetail{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return MusicDetail{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return GetMusicDetailFromDB(musicID)
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeRow 按顺序把 values 写入 Scan 的目标
+type fakeRow []interface{}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r[i]))
+	}
+	return nil
+}
+
+func TestScanMusicInfoCover(t *testing.T) {
+	tests := []struct {
+		name      string
+		width     int
+		height    int
+		wantWidth int
+	}{
+		{"original music uses avatar size", originalMusicCoverSize, originalMusicCoverSize, originalMusicCoverSize},
+		{"unknown size", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		row := fakeRow{int64(1), "原声", "作者", "cover/uri", "https://example.com/c.jpg", tt.width, tt.height,
+			"https://example.com/m.mp3", 30, "u1", "作者", true, 5}
+		music, err := scanMusicInfo(row)
+		if err != nil {
+			t.Fatalf("%s: scanMusicInfo error: %v", tt.name, err)
+		}
+
+		want := CoverMedia{URI: "cover/uri", URLList: []string{"https://example.com/c.jpg"}, Width: tt.wantWidth, Height: tt.wantWidth}
+		for _, cover := range []CoverMedia{music.CoverHD, music.CoverLarge, music.CoverMedium, music.CoverThumb} {
+			if !reflect.DeepEqual(cover, want) {
+				t.Errorf("%s: cover = %+v, want %+v", tt.name, cover, want)
+			}
+		}
+		if music.UserCount != 5 || music.Duration != 30 {
+			t.Errorf("%s: user count %d, duration %d, want 5, 30", tt.name, music.UserCount, music.Duration)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// nearbyWindowDays 同城页只推荐最近发布的视频
+const nearbyWindowDays = 30
+
+// Location 地理位置
+type Location struct {
+	Country  string `json:"country"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+}
+
+// normalizeCity 统一城市名称，去掉首尾空白和“市”后缀，与 cityColumn 一致
+func normalizeCity(city string) string {
+	return strings.TrimSuffix(strings.TrimSpace(city), "市")
+}
+
+// cityColumn 返回按 normalizeCity 规则统一后的城市字段表达式
+func cityColumn(column string) string {
+	return `rtrim(btrim(COALESCE(` + column + `, '')), '市')`
+}
+
+// userHidesCity 返回用户隐藏了所在城市的查询条件，userColumn 为用户 uid 字段
+func userHidesCity(userColumn string) string {
+	return `COALESCE((SELECT ps.hide_city FROM user_privacy_settings ps WHERE ps.user_id = ` + userColumn + `), false)`
+}
+
+// getUserLocation 获取用户资料中的所在地，用户不存在时返回空位置
+func getUserLocation(userID string) (Location, error) {
+	var location Location
+	err := config.DB.QueryRow(`
+		SELECT COALESCE(country, ''), COALESCE(province, ''), COALESCE(city, '')
+		FROM users WHERE uid = $1
+	`, userID).Scan(&location.Country, &location.Province, &location.City)
+	if err != nil && err != sql.ErrNoRows {
+		return Location{}, fmt.Errorf("查询用户所在地失败: %v", err)
+	}
+	return location, nil
+}
+
+// getIPLocation 从 IP 归属地表查询 IP 所在地，取最精确的网段；无法识别时返回空位置
+func getIPLocation(ip string) (Location, error) {
+	if net.ParseIP(ip) == nil {
+		return Location{}, nil
+	}
+
+	var location Location
+	err := config.DB.QueryRow(`
+		SELECT COALESCE(country, ''), COALESCE(province, ''), COALESCE(city, '')
+		FROM ip_regions
+		WHERE network >>= $1::inet
+		ORDER BY masklen(network) DESC
+		LIMIT 1
+	`, ip).Scan(&location.Country, &location.Province, &location.City)
+	if err != nil && err != sql.ErrNoRows {
+		return Location{}, fmt.Errorf("查询 IP 归属地失败: %v", err)
+	}
+	return location, nil
+}
+
+// locationLookup 按用户ID或 IP 查询所在地
+type locationLookup func(string) (Location, error)
+
+// resolveViewerLocation 获取观看者所在地，资料中没有填写城市时根据请求 IP 推断
+func resolveViewerLocation(viewerID, clientIP string, byUser, byIP locationLookup) (Location, error) {
+	location, err := byUser(viewerID)
+	if err != nil {
+		return Location{}, err
+	}
+	if normalizeCity(location.City) != "" {
+		return location, nil
+	}
+	return byIP(clientIP)
+}
+
+// nearbyCandidates 召回最近发布的同城视频：视频地区与观看者城市一致，或作者所在城市一致且作者没有隐藏城市
+func nearbyCandidates(req rankRequest, limit int) ([]rankCandidate, error) {
+	query := `
+		SELECT ` + rankCandidateColumns + `
+		FROM videos v
+		LEFT JOIN video_statistics vs ON v.id = vs.video_id
+		LEFT JOIN users u ON v.author_user_id = u.uid
+		WHERE ` + rankCandidateFilter("$1", "$3") + ` AND v.create_time >= $4
+		  AND (` + cityColumn("v.region") + ` = $5
+		       OR (` + cityColumn("u.city") + ` = $5 AND NOT ` + userHidesCity("u.uid") + `))
+		ORDER BY v.create_time DESC
+		LIMIT $2
+	`
+	since := req.Now.AddDate(0, 0, -nearbyWindowDays).Unix()
+	return queryRankCandidates(query, req.ViewerID, limit, req.Now.Unix(), since, req.City)
+}
+
+// nearbyRanker 同城页使用的排序器，打分和打散规则与推荐列表相同
+func nearbyRanker() *ranker {
+	r := defaultRanker()
+	r.Generators = []candidateGenerator{
+		{Name: "nearby", Generate: nearbyCandidates},
+	}
+	return r
+}
+
+// fillAuthorLocations 填充作者所在地，隐藏了城市的作者只返回省份
+func fillAuthorLocations(videos []Video) error {
+	if len(videos) == 0 {
+		return nil
+	}
+	authorIDs := make([]string, len(videos))
+	for i, video := range videos {
+		authorIDs[i] = video.AuthorUserID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT u.uid, COALESCE(u.country, ''), COALESCE(u.province, ''),
+		       CASE WHEN `+userHidesCity("u.uid")+` THEN '' ELSE COALESCE(u.city, '') END
+		FROM users u
+		WHERE u.uid = ANY($1)
+	`, pq.Array(authorIDs))
+	if err != nil {
+		return fmt.Errorf("查询作者所在地失败: %v", err)
+	}
+	defer rows.Close()
+
+	locations := make(map[string]Location)
+	for rows.Next() {
+		var uid string
+		var location Location
+		if err := rows.Scan(&uid, &location.Country, &location.Province, &location.City); err != nil {
+			return fmt.Errorf("解析作者所在地失败: %v", err)
+		}
+		locations[uid] = location
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询作者所在地时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		location := locations[videos[i].AuthorUserID]
+		videos[i].Author.Country = location.Country
+		videos[i].Author.Province = location.Province
+		videos[i].Author.City = location.City
+	}
+	return nil
+}
+
+// GetNearbyVideosFromDB 获取同城视频，返回当前页视频、观看者所在城市和下一页游标。
+// 第一页确定观看者所在城市并记录在游标中；无法确定城市时返回空列表
+func GetNearbyVideosFromDB(viewerID, clientIP, token string, limit int) ([]Video, string, string, error) {
+	if config.DB == nil {
+		return nil, "", "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "nearby:"+viewerID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if cursor.Snapshot == 0 {
+		location, err := resolveViewerLocation(viewerID, clientIP, getUserLocation, getIPLocation)
+		if err != nil {
+			return nil, "", "", err
+		}
+		cursor.Filter = normalizeCity(location.City)
+		cursor.Snapshot = time.Now().Unix()
+		cursor.pageKey = pageKey{}
+	}
+	if cursor.Filter == "" {
+		return []Video{}, "", "", nil
+	}
+
+	req := rankRequest{ViewerID: viewerID, Now: time.Unix(cursor.Snapshot, 0), City: cursor.Filter}
+	videos, next, err := rankedVideoPage(nearbyRanker(), req, cursor, limit)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if err := fillAuthorLocations(videos); err != nil {
+		return nil, "", "", err
+	}
+	return videos, cursor.Filter, next, nil
+}
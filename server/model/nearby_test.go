@@ -0,0 +1,55 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolveViewerLocation(t *testing.T) {
+	hangzhou := Location{Country: "中国", Province: "浙江省", City: "杭州市"}
+	shanghai := Location{Country: "中国", Province: "上海市", City: "上海市"}
+	errLookup := errors.New("lookup failed")
+
+	tests := []struct {
+		name       string
+		user       Location
+		userErr    error
+		ip         Location
+		wantIPCall bool
+		want       Location
+		wantErr    error
+	}{
+		{name: "profile city wins", user: hangzhou, ip: shanghai, want: hangzhou},
+		{name: "no profile city falls back to ip", user: Location{Country: "中国", Province: "浙江省"}, ip: shanghai, wantIPCall: true, want: shanghai},
+		{name: "blank city falls back to ip", user: Location{City: " 市"}, ip: shanghai, wantIPCall: true, want: shanghai},
+		{name: "unknown ip", wantIPCall: true},
+		{name: "user lookup error", userErr: errLookup, ip: shanghai, wantErr: errLookup},
+	}
+	for _, tt := range tests {
+		ipCalled := false
+		byUser := func(userID string) (Location, error) {
+			if userID != "u1" {
+				t.Errorf("%s: user lookup got %q, want u1", tt.name, userID)
+			}
+			return tt.user, tt.userErr
+		}
+		byIP := func(ip string) (Location, error) {
+			ipCalled = true
+			if ip != "1.2.3.4" {
+				t.Errorf("%s: ip lookup got %q, want 1.2.3.4", tt.name, ip)
+			}
+			return tt.ip, nil
+		}
+
+		got, err := resolveViewerLocation("u1", "1.2.3.4", byUser, byIP)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("%s: location = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if ipCalled != tt.wantIPCall {
+			t.Errorf("%s: ip lookup called = %v, want %v", tt.name, ipCalled, tt.wantIPCall)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+	"time"
+)
+
+// 通知类型
+const (
+	NoticeTypeNewVideo = "new_video" // 关注的作者发布了新视频
+	NoticeTypeNewPost  = "new_post"  // 关注的作者发布了新图文
+)
+
+// Notification 通知
+type Notification struct {
+	ID         int64  `json:"id"`
+	NoticeType string `json:"notice_type"`
+	AwemeID    string `json:"aweme_id"`
+	Content    string `json:"content"`
+	IsRead     bool   `json:"is_read"`
+	CreateTime int64  `json:"create_time"`
+	Sender     User   `json:"sender"`
+}
+
+// notifyFollowers 给作者的所有关注者发送通知
+func notifyFollowers(tx *sql.Tx, authorID, noticeType, awemeID, content string) error {
+	_, err := tx.Exec(`
+		INSERT INTO notifications (user_id, sender_id, notice_type, aweme_id, content)
+		SELECT follower_id, following_id, $2, $3, $4
+		FROM user_follows
+		WHERE following_id = $1
+	`, authorID, noticeType, awemeID, content)
+	if err != nil {
+		return fmt.Errorf("发送关注者通知失败: %v", err)
+	}
+	return nil
+}
+
+// GetNotificationsFromDB 获取用户通知列表，最新的在前，返回下一页游标
+func GetNotificationsFromDB(userID, token string, limit int) ([]Notification, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "notification:"+userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	createdAt := timestampPageKey("n.created_at")
+	query := `
+		SELECT n.id, n.notice_type, COALESCE(n.aweme_id, ''), COALESCE(n.content, ''), n.is_read, n.created_at,
+		       COALESCE(u.uid, ''), COALESCE(u.nickname, ''), COALESCE(u.avatar_168x168_url, ''),
+		       ` + createdAt + `
+		FROM notifications n
+		LEFT JOIN users u ON n.sender_id = u.uid
+		WHERE n.user_id = $1 AND (` + createdAt + `, n.id) < ($2, $3)
+		ORDER BY ` + createdAt + ` DESC, n.id DESC
+		LIMIT $4
+	`
+	rows, err := config.DB.Query(query, userID, cursor.Key, cursor.ID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询通知失败: %v", err)
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	var keys []pageKey
+	for rows.Next() {
+		var n Notification
+		var createdAt time.Time
+		var avatarURL string
+		var key pageKey
+		err := rows.Scan(
+			&n.ID, &n.NoticeType, &n.AwemeID, &n.Content, &n.IsRead, &createdAt,
+			&n.Sender.UID, &n.Sender.Nickname, &avatarURL,
+			&key.Key,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("解析通知失败: %v", err)
+		}
+		key.ID = n.ID
+		n.CreateTime = createdAt.Unix()
+		n.Sender.Avatar168x168 = AvatarInfo{URLList: []string{avatarURL}}
+		notifications = append(notifications, n)
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("查询通知时发生错误: %v", err)
+	}
+
+	notifications, next := cutPage(notifications, keys, limit, cursor)
+	return notifications, next, nil
+}
+
+// GetNotificationCountFromDB 获取用户通知总数
+func GetNotificationCountFromDB(userID string) (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	var count int
+	err := config.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("查询通知总数失败: %v", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+
+	"github.com/lib/pq"
+)
+
+// VideoTypePhoto 图文作品的视频类型，图文作品没有播放地址，由多张图片组成
+const VideoTypePhoto = "photo"
+
+// 作品类型，对应 Video.AwemeType
+const (
+	AwemeTypeVideo = 0  // 视频
+	AwemeTypePhoto = 68 // 图文
+)
+
+// MaxPhotoImages 图文作品最多图片数，图文草稿使用同一上限，保证草稿都能发布
+const MaxPhotoImages = 35
+
+// ErrInvalidPhotoPost 图文作品参数无效
+var ErrInvalidPhotoPost = errors.New("图文作品参数无效")
+
+// PhotoImage 图文作品中的图片
+type PhotoImage struct {
+	URI     string   `json:"uri"`
+	URLList []string `json:"url_list"`
+	Width   int      `json:"width"`
+	Height  int      `json:"height"`
+}
+
+// PhotoImageFile 发布图文作品时上传的图片
+type PhotoImageFile struct {
+	URL    string
+	Width  int
+	Height int
+}
+
+// validatePhotoPost 校验图文作品参数，图片按顺序展示，背景音乐只能选择已有音乐
+func validatePhotoPost(params VideoCreateParams) error {
+	if len(params.Images) == 0 || len(params.Images) > MaxPhotoImages {
+		return fmt.Errorf("%w: 图片数量必须在 1 到 %d 张之间", ErrInvalidPhotoPost, MaxPhotoImages)
+	}
+	if params.PlayURL != "" {
+		return fmt.Errorf("%w: 图文作品不能包含视频", ErrInvalidPhotoPost)
+	}
+	if params.OriginalSound {
+		return fmt.Errorf("%w: 图文作品没有原声，只能选择已有音乐", ErrInvalidMusic)
+	}
+	for i, image := range params.Images {
+		if image.URL == "" || image.Width <= 0 || image.Height <= 0 {
+			return fmt.Errorf("%w: 第 %d 张图片无效", ErrInvalidPhotoPost, i+1)
+		}
+	}
+	return nil
+}
+
+// insertPhotoImages 按顺序保存图文作品的图片
+func insertPhotoImages(tx *sql.Tx, videoID int64, images []PhotoImageFile) error {
+	for i, image := range images {
+		_, err := tx.Exec(`
+			INSERT INTO video_images (video_id, image_index, uri, url, width, height)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, videoID, i, mediaURI(image.URL), image.URL, image.Width, image.Height)
+		if err != nil {
+			return fmt.Errorf("保存图文图片失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// fillVideoImages 填充图文作品的图片列表，图文作品没有播放地址
+func fillVideoImages(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, COALESCE(vi.uri, ''), vi.url, vi.width, vi.height
+		FROM video_images vi
+		JOIN videos v ON vi.video_id = v.id
+		WHERE v.aweme_id = ANY($1)
+		ORDER BY vi.image_index
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询图文图片失败: %v", err)
+	}
+	defer rows.Close()
+
+	images := make(map[string][]PhotoImage)
+	for rows.Next() {
+		var awemeID, url string
+		var image PhotoImage
+		if err := rows.Scan(&awemeID, &image.URI, &url, &image.Width, &image.Height); err != nil {
+			return fmt.Errorf("解析图文图片失败: %v", err)
+		}
+		image.URLList = []string{url}
+		images[awemeID] = append(images[awemeID], image)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询图文图片时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		list, ok := images[videos[i].AwemeID]
+		if !ok {
+			videos[i].AwemeType = AwemeTypeVideo
+			continue
+		}
+		videos[i].AwemeType = AwemeTypePhoto
+		videos[i].Images = list
+		videos[i].VideoInfo.PlayAddr = PlayAddr{URLList: []string{}}
+		videos[i].VideoInfo.Width = list[0].Width
+		videos[i].VideoInfo.Height = list[0].Height
+	}
+	return nil
+}
@@ -7,28 +7,36 @@ import (
 	"log"
 )
 
-// GetRecommendPostsFromDB 从数据库获取推荐帖子
-func GetRecommendPostsFromDB(offset, limit int) ([]Post, error) {
+// GetRecommendPostsFromDB 从数据库获取对观看者可见的推荐帖子，最新发布的在前，返回下一页游标
+func GetRecommendPostsFromDB(viewerID, token string, limit int) ([]Post, string, error) {
 	if config.DB == nil {
-		return nil, fmt.Errorf("数据库未初始化")
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "post")
+	if err != nil {
+		return nil, "", err
 	}
 
 	// 查询帖子数据
 	query := `
-		SELECT p.id, p.post_id, p.text, p.post_id, p.create_time, p.digg_count, p.comment_count, p.share_count
+		SELECT p.id, p.post_id, COALESCE(p.post_text, p.description, ''), COALESCE(u.id, 0),
+		       COALESCE(p.create_time, 0), p.digg_count, p.comment_count, p.share_count
 		FROM posts p
-		ORDER BY p.create_time DESC
-		LIMIT $1 OFFSET $2
+		LEFT JOIN users u ON p.author_user_id = u.uid
+		WHERE (COALESCE(p.create_time, 0), p.id) < ($1, $2) AND ` + postVisibleCondition("$4") + `
+		ORDER BY COALESCE(p.create_time, 0) DESC, p.id DESC
+		LIMIT $3
 	`
-	rows, err := config.DB.Query(query, limit, offset)
+	rows, err := config.DB.Query(query, cursor.Key, cursor.ID, limit+1, viewerID)
 	if err != nil {
-		return nil, fmt.Errorf("查询帖子数据失败: %v", err)
+		return nil, "", fmt.Errorf("查询帖子数据失败: %v", err)
 	}
 	defer rows.Close()
 
 	// 处理查询结果
-	var posts []Post
+	posts := []Post{}
 	var dbPosts []DBPost
+	var keys []pageKey
 
 	for rows.Next() {
 		var post DBPost
@@ -43,10 +51,12 @@ func GetRecommendPostsFromDB(offset, limit int) ([]Post, error) {
 			&post.ShareCount,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("扫描帖子数据失败: %v", err)
+			return nil, "", fmt.Errorf("扫描帖子数据失败: %v", err)
 		}
 		dbPosts = append(dbPosts, post)
+		keys = append(keys, pageKey{Key: post.CreateTime, ID: int64(post.ID)})
 	}
+	dbPosts, next := cutPage(dbPosts, keys, limit, cursor)
 
 	// 获取每个帖子的作者信息和图片
 	for i, post := range dbPosts {
@@ -66,6 +76,13 @@ func GetRecommendPostsFromDB(offset, limit int) ([]Post, error) {
 		}
 		dbPosts[i].Images = images
 
+		// 获取帖子话题和 @用户
+		textExtra, err := getPostTextExtraFromDB(post.ID)
+		if err != nil {
+			log.Printf("获取帖子文本额外信息失败: %v", err)
+			continue
+		}
+
 		// 转换为Post模型
 		posts = append(posts, Post{
 			ID:           post.PostID,
@@ -76,22 +93,23 @@ func GetRecommendPostsFromDB(offset, limit int) ([]Post, error) {
 			CommentCount: post.CommentCount,
 			ShareCount:   post.ShareCount,
 			Images:       post.Images,
+			TextExtra:    textExtra,
 		})
 	}
 
-	return posts, nil
+	return posts, next, nil
 }
 
-// GetPostCountFromDB 获取帖子总数
-func GetPostCountFromDB() (int, error) {
+// GetPostCountFromDB 获取对观看者可见的帖子总数
+func GetPostCountFromDB(viewerID string) (int, error) {
 	if config.DB == nil {
 		return 0, fmt.Errorf("数据库未初始化")
 	}
 
 	// 查询帖子总数
 	var count int
-	query := "SELECT COUNT(*) FROM posts"
-	err := config.DB.QueryRow(query).Scan(&count)
+	query := "SELECT COUNT(*) FROM posts p WHERE " + postVisibleCondition("$1")
+	err := config.DB.QueryRow(query, viewerID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("查询帖子总数失败: %v", err)
 	}
@@ -181,27 +199,73 @@ func getPostImagesFromDB(postID int) ([]string, error) {
 	return images, nil
 }
 
-// GetGoodsFromDB 从数据库获取商品
-func GetGoodsFromDB(offset, limit int) ([]Good, error) {
+// getPostTextExtraFromDB 从数据库获取帖子的话题和 @用户
+func getPostTextExtraFromDB(postID int) ([]TextExtra, error) {
 	if config.DB == nil {
 		return nil, fmt.Errorf("数据库未初始化")
 	}
 
+	// 查询帖子文本额外信息
+	query := `
+		SELECT pte.start_index, pte.end_index, pte.extra_type, COALESCE(pte.hashtag_name, ''),
+		       COALESCE(pte.hashtag_id, ''), COALESCE(pte.user_id, ''), COALESCE(u.sec_uid, ''),
+		       COALESCE(pte.is_commerce, false), COALESCE(pte.caption_start, 0), COALESCE(pte.caption_end, 0)
+		FROM post_text_extra pte
+		LEFT JOIN users u ON pte.user_id = u.uid
+		WHERE pte.post_id = $1
+		ORDER BY pte.start_index
+	`
+	rows, err := config.DB.Query(query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("查询帖子文本额外信息失败: %v", err)
+	}
+	defer rows.Close()
+
+	// 处理查询结果
+	textExtra := []TextExtra{}
+	for rows.Next() {
+		var extra TextExtra
+		err := rows.Scan(
+			&extra.Start, &extra.End, &extra.Type, &extra.HashtagName,
+			&extra.HashtagID, &extra.UserID, &extra.SecUID,
+			&extra.IsCommerce, &extra.CaptionStart, &extra.CaptionEnd,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("扫描帖子文本额外信息失败: %v", err)
+		}
+		textExtra = append(textExtra, extra)
+	}
+
+	return textExtra, nil
+}
+
+// GetGoodsFromDB 从数据库获取商品，销量高的在前，返回下一页游标
+func GetGoodsFromDB(token string, limit int) ([]Good, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "goods")
+	if err != nil {
+		return nil, "", err
+	}
+
 	// 查询商品数据
 	query := `
 		SELECT id, good_id, title, description, price, image, sale_count
 		FROM goods
-		ORDER BY sale_count DESC
-		LIMIT $1 OFFSET $2
+		WHERE (COALESCE(sale_count, 0), id) < ($1, $2)
+		ORDER BY COALESCE(sale_count, 0) DESC, id DESC
+		LIMIT $3
 	`
-	rows, err := config.DB.Query(query, limit, offset)
+	rows, err := config.DB.Query(query, cursor.Key, cursor.ID, limit+1)
 	if err != nil {
-		return nil, fmt.Errorf("查询商品数据失败: %v", err)
+		return nil, "", fmt.Errorf("查询商品数据失败: %v", err)
 	}
 	defer rows.Close()
 
 	// 处理查询结果
-	var goods []Good
+	goods := []Good{}
+	var keys []pageKey
 	for rows.Next() {
 		var dbGood DBGood
 		err := rows.Scan(
@@ -214,7 +278,7 @@ func GetGoodsFromDB(offset, limit int) ([]Good, error) {
 			&dbGood.SaleCount,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("扫描商品数据失败: %v", err)
+			return nil, "", fmt.Errorf("扫描商品数据失败: %v", err)
 		}
 
 		// 转换为Good模型
@@ -226,9 +290,11 @@ func GetGoodsFromDB(offset, limit int) ([]Good, error) {
 			Image:       dbGood.Image,
 			SaleCount:   dbGood.SaleCount,
 		})
+		keys = append(keys, pageKey{Key: int64(dbGood.SaleCount), ID: int64(dbGood.ID)})
 	}
 
-	return goods, nil
+	goods, next := cutPage(goods, keys, limit, cursor)
+	return goods, next, nil
 }
 
 // GetGoodCountFromDB 获取商品总数
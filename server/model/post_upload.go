@@ -0,0 +1,71 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PostCreateParams 发布图文参数
+type PostCreateParams struct {
+	AuthorUserID  string
+	Desc          string
+	ImageURLs     []string
+	CoverURL      string // 封面地址，为空时使用第一张图片
+	MusicID       int64  // 配乐ID，0 表示无配乐
+	PrivateStatus int
+}
+
+// insertPost 在事务中写入图文及其关联表，返回图文ID
+func insertPost(tx *sql.Tx, params PostCreateParams) (string, error) {
+	privateStatus := params.PrivateStatus
+	if err := validateVideoPrivacy(&privateStatus, nil); err != nil {
+		return "", err
+	}
+
+	awemeID := newAwemeID()
+	var postID int64
+	err := tx.QueryRow(`
+		INSERT INTO posts (aweme_id, post_id, description, post_text, author_user_id, create_time, music_id)
+		VALUES ($1, $1, $2, $2, $3, $4, NULLIF($5, 0))
+		RETURNING id
+	`, awemeID, params.Desc, params.AuthorUserID, time.Now().Unix(), params.MusicID).Scan(&postID)
+	if err != nil {
+		return "", fmt.Errorf("保存图文失败: %v", err)
+	}
+
+	for _, url := range params.ImageURLs {
+		if _, err := tx.Exec(`INSERT INTO post_images (post_id, image_url) VALUES ($1, $2)`, postID, url); err != nil {
+			return "", fmt.Errorf("保存图文图片失败: %v", err)
+		}
+	}
+
+	coverURL := params.CoverURL
+	if coverURL == "" && len(params.ImageURLs) > 0 {
+		coverURL = params.ImageURLs[0]
+	}
+	if coverURL != "" {
+		_, err = tx.Exec(`INSERT INTO post_covers (post_id, uri, url) VALUES ($1, $2, $3)`, postID, mediaURI(coverURL), coverURL)
+		if err != nil {
+			return "", fmt.Errorf("保存图文封面失败: %v", err)
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO post_status (post_id, private_status) VALUES ($1, $2)`, postID, privateStatus)
+	if err != nil {
+		return "", fmt.Errorf("保存图文状态失败: %v", err)
+	}
+
+	if err := savePostMentions(tx, postID, params.AuthorUserID, awemeID, params.Desc); err != nil {
+		return "", err
+	}
+
+	// 仅公开图文通知关注者
+	if privateStatus == VideoPrivacyPublic {
+		if err := notifyFollowers(tx, params.AuthorUserID, NoticeTypeNewPost, awemeID, "发布了新图文："+params.Desc); err != nil {
+			return "", err
+		}
+	}
+
+	return awemeID, nil
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"klik/server/config"
+)
+
+var (
+	// ErrUserNotFound 用户不存在
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrCannotBlockSelf 不能拉黑自己
+	ErrCannotBlockSelf = errors.New("不能拉黑自己")
+)
+
+// PrivacySettings 用户隐私设置
+type PrivacySettings struct {
+	AllowMention bool `json:"allow_mention"` // 是否允许别人 @ 自己
+	HideCity     bool `json:"hide_city"`     // 是否隐藏所在城市，隐藏后只显示省份，也不按所在城市出现在同城页
+}
+
+// PrivacySettingsParams 修改隐私设置参数，为空的字段保持不变
+type PrivacySettingsParams struct {
+	AllowMention *bool `json:"allow_mention"`
+	HideCity     *bool `json:"hide_city"`
+}
+
+// GetPrivacySettingsFromDB 获取用户隐私设置，没有记录时返回默认设置
+func GetPrivacySettingsFromDB(userID string) (PrivacySettings, error) {
+	if config.DB == nil {
+		return PrivacySettings{}, fmt.Errorf("数据库未初始化")
+	}
+
+	settings := PrivacySettings{AllowMention: true}
+	err := config.DB.QueryRow(`
+		SELECT COALESCE((SELECT allow_mention FROM user_privacy_settings WHERE user_id = $1), true),
+		       COALESCE((SELECT hide_city FROM user_privacy_settings WHERE user_id = $1), false)
+	`, userID).Scan(&settings.AllowMention, &settings.HideCity)
+	if err != nil {
+		return PrivacySettings{}, fmt.Errorf("查询隐私设置失败: %v", err)
+	}
+	return settings, nil
+}
+
+// UpdatePrivacySettingsFromDB 修改用户隐私设置
+func UpdatePrivacySettingsFromDB(userID string, params PrivacySettingsParams) (PrivacySettings, error) {
+	if config.DB == nil {
+		return PrivacySettings{}, fmt.Errorf("数据库未初始化")
+	}
+
+	var settings PrivacySettings
+	err := config.DB.QueryRow(`
+		INSERT INTO user_privacy_settings (user_id, allow_mention, hide_city)
+		VALUES ($1, COALESCE($2, true), COALESCE($3, false))
+		ON CONFLICT (user_id) DO UPDATE SET
+			allow_mention = COALESCE($2, user_privacy_settings.allow_mention),
+			hide_city = COALESCE($3, user_privacy_settings.hide_city),
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING allow_mention, hide_city
+	`, userID, params.AllowMention, params.HideCity).Scan(&settings.AllowMention, &settings.HideCity)
+	if err != nil {
+		return PrivacySettings{}, fmt.Errorf("修改隐私设置失败: %v", err)
+	}
+	return settings, nil
+}
+
+// BlockUserFromDB 拉黑用户，同时解除双方的关注关系
+func BlockUserFromDB(userID, targetID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if userID == targetID {
+		return ErrCannotBlockSelf
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE uid = $1)`, targetID).Scan(&exists); err != nil {
+		return fmt.Errorf("查询用户失败: %v", err)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`, userID, targetID)
+	if err != nil {
+		return fmt.Errorf("拉黑用户失败: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM user_follows
+		WHERE (follower_id = $1 AND following_id = $2) OR (follower_id = $2 AND following_id = $1)
+	`, userID, targetID)
+	if err != nil {
+		return fmt.Errorf("解除关注关系失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// UnblockUserFromDB 取消拉黑
+func UnblockUserFromDB(userID, targetID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	_, err := config.DB.Exec(`DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`, userID, targetID)
+	if err != nil {
+		return fmt.Errorf("取消拉黑失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,498 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 推荐排序默认参数，可在配置文件 recommend 节中覆盖
+const (
+	defaultRankCandidateLimit = 300  // 每个召回通道最多返回的候选数
+	defaultRankHalfLifeHours  = 48   // 时间衰减半衰期（小时）
+	defaultRankAuthorDecay    = 0.5  // 同一作者每多出现一次，得分乘以该系数
+	rankPopularWindowDays     = 30   // 热门召回只考虑最近发布的视频
+	rankPriorPlays            = 20   // 计算互动率时的平滑播放数，避免播放很少的视频互动率虚高
+	rankFinishPercent         = 90   // 观看时长达到视频时长的该百分比视为完播
+	rankMinScore              = 1e-9 // 得分下限，避免衰减后得分为 0 无法区分
+)
+
+// recommendVideoTypes 推荐列表包含的作品类型
+const recommendVideoTypes = `('recommend-video', 'photo')`
+
+// rankCandidate 推荐候选视频及打分所需的统计特征
+type rankCandidate struct {
+	VideoID      int64
+	AwemeID      string
+	AuthorUserID string
+	CreateTime   int64
+	PlayCount    int
+	FinishCount  int
+	DiggCount    int
+	CommentCount int
+	ShareCount   int
+	CollectCount int
+	Sources      []string // 召回该候选的通道
+	Similarity   float64  // 与观看者喜欢过的视频的相似度，只有相似视频召回的候选有值
+	SeedAwemeID  string   // 相似度对应的观看者喜欢过的视频
+	Score        float64
+}
+
+// rankRequest 一次推荐请求的上下文。Now 为排序快照时间，
+// 之后发布的视频和之后的观看记录不影响本次排序，翻页时沿用第一页的快照保证结果稳定
+type rankRequest struct {
+	ViewerID string
+	Now      time.Time
+	City     string   // 同城页观看者所在城市
+	Seen     *seenSet // 观看者已曝光的视频，翻页时跳过，为 nil 时不过滤
+}
+
+// candidateGenerator 召回通道，返回对观看者可见且未看过的候选视频
+type candidateGenerator struct {
+	Name     string
+	Generate func(req rankRequest, limit int) ([]rankCandidate, error)
+}
+
+// candidateScorer 打分器，返回候选的得分系数，候选最终得分为各打分器系数之积
+type candidateScorer struct {
+	Name  string
+	Score func(req rankRequest, candidate *rankCandidate) float64
+}
+
+// ranker 推荐排序器：多路召回合并去重，逐个打分后按作者打散
+type ranker struct {
+	Generators     []candidateGenerator
+	Scorers        []candidateScorer
+	CandidateLimit int
+	AuthorDecay    float64
+}
+
+// defaultRanker 推荐列表使用的排序器
+func defaultRanker() *ranker {
+	cfg := config.AppConfig.Recommend
+	limit := cfg.CandidateLimit
+	if limit <= 0 {
+		limit = defaultRankCandidateLimit
+	}
+	halfLife := cfg.HalfLifeHours
+	if halfLife <= 0 {
+		halfLife = defaultRankHalfLifeHours
+	}
+	authorDecay := cfg.AuthorDecay
+	if authorDecay <= 0 || authorDecay > 1 {
+		authorDecay = defaultRankAuthorDecay
+	}
+
+	return &ranker{
+		Generators: []candidateGenerator{
+			{Name: "recent", Generate: recentCandidates},
+			{Name: "popular", Generate: popularCandidates},
+			{Name: "similar", Generate: similarCandidates},
+		},
+		Scorers: []candidateScorer{
+			{Name: "engagement", Score: engagementScore},
+			{Name: "time_decay", Score: timeDecayScorer(halfLife)},
+			{Name: "similarity", Score: similarityScore},
+		},
+		CandidateLimit: limit,
+		AuthorDecay:    authorDecay,
+	}
+}
+
+// 推荐策略，A/B 实验的分组通过配置中的 strategy 选择
+const (
+	RecommendStrategyDefault        = "default"         // 默认排序
+	RecommendStrategyNoSimilar      = "no_similar"      // 不使用相似视频召回和相似度加分
+	RecommendStrategyNoDiversity    = "no_diversity"    // 不按作者打散
+	RecommendStrategyEngagementOnly = "engagement_only" // 只按互动率排序，不做时间衰减
+)
+
+// recommendStrategies 各推荐策略在默认排序器基础上的调整
+var recommendStrategies = map[string]func(r *ranker){
+	RecommendStrategyDefault: func(r *ranker) {},
+	RecommendStrategyNoSimilar: func(r *ranker) {
+		r.Generators = withoutGenerator(r.Generators, "similar")
+		r.Scorers = withoutScorer(r.Scorers, "similarity")
+	},
+	RecommendStrategyNoDiversity: func(r *ranker) {
+		r.AuthorDecay = 1
+	},
+	RecommendStrategyEngagementOnly: func(r *ranker) {
+		r.Scorers = withoutScorer(r.Scorers, "time_decay")
+	},
+}
+
+// strategyRanker 返回推荐策略对应的排序器。配置中的策略在启动时已校验，未知策略只会来自未配置的实验，使用默认排序器
+func strategyRanker(strategy string) *ranker {
+	r := defaultRanker()
+	if apply, ok := recommendStrategies[strategy]; ok {
+		apply(r)
+	}
+	return r
+}
+
+// withoutGenerator 去掉指定名称的召回通道
+func withoutGenerator(generators []candidateGenerator, name string) []candidateGenerator {
+	result := make([]candidateGenerator, 0, len(generators))
+	for _, generator := range generators {
+		if generator.Name != name {
+			result = append(result, generator)
+		}
+	}
+	return result
+}
+
+// withoutScorer 去掉指定名称的打分器
+func withoutScorer(scorers []candidateScorer, name string) []candidateScorer {
+	result := make([]candidateScorer, 0, len(scorers))
+	for _, scorer := range scorers {
+		if scorer.Name != name {
+			result = append(result, scorer)
+		}
+	}
+	return result
+}
+
+// Rank 召回、打分并打散，返回按推荐顺序排列的候选
+func (r *ranker) Rank(req rankRequest) ([]rankCandidate, error) {
+	if req.Now.IsZero() {
+		req.Now = time.Now()
+	}
+
+	// 多路召回，同一视频只保留一份并记录全部来源和最高的相似度
+	var candidates []rankCandidate
+	index := make(map[int64]int)
+	for _, generator := range r.Generators {
+		list, err := generator.Generate(req, r.CandidateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("召回 %s 失败: %v", generator.Name, err)
+		}
+		for _, candidate := range list {
+			if i, ok := index[candidate.VideoID]; ok {
+				candidates[i].Sources = append(candidates[i].Sources, generator.Name)
+				if candidate.Similarity > candidates[i].Similarity {
+					candidates[i].Similarity = candidate.Similarity
+					candidates[i].SeedAwemeID = candidate.SeedAwemeID
+				}
+				continue
+			}
+			candidate.Sources = []string{generator.Name}
+			index[candidate.VideoID] = len(candidates)
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	// 打分
+	for i := range candidates {
+		score := 1.0
+		for _, scorer := range r.Scorers {
+			score *= scorer.Score(req, &candidates[i])
+		}
+		candidates[i].Score = math.Max(score, rankMinScore)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].VideoID > candidates[j].VideoID
+	})
+
+	return diversifyByAuthor(candidates, r.AuthorDecay), nil
+}
+
+// diversifyByAuthor 按作者打散：依次选出调整后得分最高的候选，
+// 作者每已入选一个视频，其余视频的得分乘以一次 decay。输入需已按得分降序排列
+func diversifyByAuthor(candidates []rankCandidate, decay float64) []rankCandidate {
+	if decay >= 1 || len(candidates) < 2 {
+		return candidates
+	}
+
+	result := make([]rankCandidate, 0, len(candidates))
+	picked := make([]bool, len(candidates))
+	authorCount := make(map[string]int)
+	for len(result) < len(candidates) {
+		best, bestScore := -1, 0.0
+		for i, candidate := range candidates {
+			if picked[i] {
+				continue
+			}
+			score := candidate.Score * math.Pow(decay, float64(authorCount[candidate.AuthorUserID]))
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked[best] = true
+		authorCount[candidates[best].AuthorUserID]++
+		result = append(result, candidates[best])
+	}
+	return result
+}
+
+// rankCandidateColumns 召回查询的字段，与 queryRankCandidates 的扫描顺序对应，要求视频表别名为 v
+const rankCandidateColumns = `
+	v.id, v.aweme_id, COALESCE(v.author_user_id, ''), COALESCE(v.create_time, 0),
+	COALESCE(vs.play_count, 0), COALESCE(vs.finish_count, 0), COALESCE(vs.digg_count, 0),
+	COALESCE(vs.comment_count, 0), COALESCE(vs.share_count, 0), COALESCE(vs.collect_count, 0)`
+
+// rankCandidateFilter 所有召回通道共用的过滤条件：推荐类型、对观看者可见、在快照时间前发布且观看者在快照时间前未看过，
+// viewerParam 为观看者ID的占位符，nowParam 为快照时间（秒级时间戳）的占位符
+func rankCandidateFilter(viewerParam, nowParam string) string {
+	return `v.video_type IN ` + recommendVideoTypes + ` AND ` + videoVisibleCondition(viewerParam) + `
+		AND COALESCE(v.create_time, 0) <= ` + nowParam + `
+		AND NOT EXISTS (
+			SELECT 1 FROM user_history_videos uhv
+			WHERE uhv.video_id = v.id AND uhv.commenter_id = ` + viewerParam + `
+			  AND uhv.view_time < to_timestamp(` + nowParam + `)
+		)`
+}
+
+// scanRankCandidate 解析一行 rankCandidateColumns，extra 接收之后的额外字段
+func scanRankCandidate(rows *sql.Rows, c *rankCandidate, extra ...interface{}) error {
+	dest := []interface{}{
+		&c.VideoID, &c.AwemeID, &c.AuthorUserID, &c.CreateTime,
+		&c.PlayCount, &c.FinishCount, &c.DiggCount,
+		&c.CommentCount, &c.ShareCount, &c.CollectCount,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return fmt.Errorf("解析候选视频失败: %v", err)
+	}
+	return nil
+}
+
+// queryRankCandidates 执行召回查询
+func queryRankCandidates(query string, args ...interface{}) ([]rankCandidate, error) {
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询候选视频失败: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []rankCandidate
+	for rows.Next() {
+		var c rankCandidate
+		if err := scanRankCandidate(rows, &c); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询候选视频时发生错误: %v", err)
+	}
+	return candidates, nil
+}
+
+// recentCandidates 召回最新发布的视频
+func recentCandidates(req rankRequest, limit int) ([]rankCandidate, error) {
+	query := `
+		SELECT ` + rankCandidateColumns + `
+		FROM videos v
+		LEFT JOIN video_statistics vs ON v.id = vs.video_id
+		WHERE ` + rankCandidateFilter("$1", "$3") + `
+		ORDER BY v.create_time DESC
+		LIMIT $2
+	`
+	return queryRankCandidates(query, req.ViewerID, limit, req.Now.Unix())
+}
+
+// popularCandidates 召回最近一段时间内互动最多的视频
+func popularCandidates(req rankRequest, limit int) ([]rankCandidate, error) {
+	query := `
+		SELECT ` + rankCandidateColumns + `
+		FROM videos v
+		LEFT JOIN video_statistics vs ON v.id = vs.video_id
+		WHERE ` + rankCandidateFilter("$1", "$3") + ` AND v.create_time >= $4
+		ORDER BY COALESCE(vs.digg_count, 0) + 2 * COALESCE(vs.comment_count, 0)
+		         + 3 * COALESCE(vs.share_count, 0) + 2 * COALESCE(vs.collect_count, 0) DESC
+		LIMIT $2
+	`
+	since := req.Now.AddDate(0, 0, -rankPopularWindowDays).Unix()
+	return queryRankCandidates(query, req.ViewerID, limit, req.Now.Unix(), since)
+}
+
+// engagementRate 平滑后的互动率，不超过 1
+func engagementRate(count, plays int) float64 {
+	return math.Min(float64(count)/float64(plays+rankPriorPlays), 1)
+}
+
+// engagementScore 根据完播、点赞、评论、分享、收藏率打分，越深的互动权重越高
+func engagementScore(req rankRequest, c *rankCandidate) float64 {
+	return 1 +
+		1.0*engagementRate(c.FinishCount, c.PlayCount) +
+		2.0*engagementRate(c.DiggCount, c.PlayCount) +
+		3.0*engagementRate(c.CommentCount, c.PlayCount) +
+		4.0*engagementRate(c.ShareCount, c.PlayCount) +
+		3.0*engagementRate(c.CollectCount, c.PlayCount)
+}
+
+// timeDecayScorer 按发布时间指数衰减，每经过 halfLifeHours 得分减半
+func timeDecayScorer(halfLifeHours float64) func(req rankRequest, c *rankCandidate) float64 {
+	return func(req rankRequest, c *rankCandidate) float64 {
+		ageHours := req.Now.Sub(time.Unix(c.CreateTime, 0)).Hours()
+		if ageHours < 0 {
+			ageHours = 0
+		}
+		return math.Pow(0.5, ageHours/halfLifeHours)
+	}
+}
+
+// GetRecommendVideosFromDB 获取个性化推荐视频列表，返回当前页视频和下一页游标。
+// 观看者看过的视频和在其他会话、设备上已曝光的视频不再推荐；游标记录第一页的排序快照时间和上一页最后一条的位置与ID，
+// 翻页期间新发布的视频不会插入列表。strategy 为观看者所在实验分组的推荐策略，为空时使用默认策略
+func GetRecommendVideosFromDB(viewerID, strategy, token string, limit int) ([]Video, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "recommend:"+viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if cursor.Snapshot == 0 {
+		cursor.Snapshot = time.Now().Unix()
+		cursor.pageKey = pageKey{}
+	}
+
+	seen, err := getSeenSet(viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	req := rankRequest{ViewerID: viewerID, Now: time.Unix(cursor.Snapshot, 0), Seen: seen}
+	return rankedVideoPage(strategyRanker(strategy), req, cursor, limit)
+}
+
+// rankedVideoPage 按排序器的结果取一页视频，游标中的快照时间需已设置
+func rankedVideoPage(r *ranker, req rankRequest, cursor pageCursor, limit int) ([]Video, string, error) {
+	candidates, err := r.Rank(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 从上一页最后一条之后继续；互动数据变化导致顺序改变时，按上一页最后一条的ID重新定位
+	start := int(cursor.Key)
+	if cursor.ID != 0 {
+		for i, candidate := range candidates {
+			if candidate.VideoID == cursor.ID {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if start < 0 || start > len(candidates) {
+		start = len(candidates)
+	}
+
+	// 跳过其他会话或设备上已曝光的视频；本次翻页已返回的视频都在上一页最后一条之前，不影响定位
+	var page []rankCandidate
+	end := start
+	for ; end < len(candidates) && len(page) < limit; end++ {
+		if !req.Seen.Contains(candidates[end].AwemeID) {
+			page = append(page, candidates[end])
+		}
+	}
+	hasMore := false
+	for _, candidate := range candidates[end:] {
+		if !req.Seen.Contains(candidate.AwemeID) {
+			hasMore = true
+			break
+		}
+	}
+
+	awemeIDs := make([]string, 0, len(page))
+	for _, candidate := range page {
+		awemeIDs = append(awemeIDs, candidate.AwemeID)
+	}
+	videos, err := getVideosInOrder(awemeIDs, req.ViewerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := fillRecommendReasons(videos, page, req.ViewerID); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if hasMore {
+		cursor.pageKey = pageKey{Key: int64(end), ID: candidates[end-1].VideoID}
+		next = encodeCursor(cursor)
+	}
+	return videos, next, nil
+}
+
+// getVideosInOrder 按给定顺序查询视频列表，查询期间变为不可见的视频会被跳过
+func getVideosInOrder(awemeIDs []string, viewerID string) ([]Video, error) {
+	query := `
+		SELECT ` + videoListColumns + `
+		FROM videos v
+		` + videoListJoins + `
+		WHERE v.aweme_id = ANY($1) AND ` + videoVisibleCondition("$2") + `
+	`
+	videos, err := queryVideoList(query, pq.Array(awemeIDs), viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Video, len(videos))
+	for _, video := range videos {
+		byID[video.AwemeID] = video
+	}
+	ordered := make([]Video, 0, len(videos))
+	for _, awemeID := range awemeIDs {
+		if video, ok := byID[awemeID]; ok {
+			ordered = append(ordered, video)
+		}
+	}
+	return ordered, nil
+}
+
+// RecordVideoPlayFromDB 上报一次播放：增加播放数，观看时长达到完播比例时增加完播数，
+// 并记录到观看者的观看历史。watchMs 与 videos.duration 均为毫秒，时长未知的视频不计完播
+func RecordVideoPlayFromDB(awemeID, viewerID string, watchMs int) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if watchMs < 0 {
+		watchMs = 0
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getVisibleVideoID(tx, awemeID, viewerID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE video_statistics vs
+		SET play_count = COALESCE(vs.play_count, 0) + 1,
+		    finish_count = COALESCE(vs.finish_count, 0) +
+		        CASE WHEN COALESCE(v.duration, 0) > 0 AND $2::bigint * 100 >= v.duration::bigint * $3 THEN 1 ELSE 0 END,
+		    updated_at = CURRENT_TIMESTAMP
+		FROM videos v
+		WHERE vs.video_id = v.id AND v.id = $1
+	`, videoID, watchMs, rankFinishPercent)
+	if err != nil {
+		return fmt.Errorf("更新播放统计失败: %v", err)
+	}
+
+	if viewerID != "" {
+		_, err = tx.Exec(`
+			INSERT INTO user_history_videos (commenter_id, video_id) VALUES ($1, $2)
+			ON CONFLICT (commenter_id, video_id) DO UPDATE SET view_time = CURRENT_TIMESTAMP
+		`, viewerID, videoID)
+		if err != nil {
+			return fmt.Errorf("记录观看历史失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
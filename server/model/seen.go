@@ -0,0 +1,222 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"klik/server/config"
+	"math"
+)
+
+// 已曝光视频集合默认参数，可在配置文件 seen 节中覆盖
+const (
+	defaultSeenCapacity          = 5000 // 每代布隆过滤器记录的视频数
+	defaultSeenFalsePositiveRate = 0.01 // 误判率
+	seenMaxRounds                = 5    // 按时间排列的列表跳过已曝光视频时最多查询的次数
+)
+
+// bloomFilter 布隆过滤器，bits 的长度和 hashes 由容量和误判率决定
+type bloomFilter struct {
+	bits   []byte
+	hashes int
+}
+
+// newBloomFilter 按容量和误判率创建空的布隆过滤器
+func newBloomFilter(capacity int, falsePositiveRate float64) bloomFilter {
+	m := math.Ceil(-float64(capacity) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	bytes := int(math.Ceil(m / 8))
+	if bytes < 1 {
+		bytes = 1
+	}
+	hashes := int(math.Round(float64(bytes*8) / float64(capacity) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return bloomFilter{bits: make([]byte, bytes), hashes: hashes}
+}
+
+// positions 返回 key 对应的各个比特位置，使用双重哈希由一次 64 位哈希生成 hashes 个位置
+func (f bloomFilter) positions(key string) []uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	m := uint64(len(f.bits)) * 8
+
+	positions := make([]uint64, f.hashes)
+	for i := range positions {
+		positions[i] = (h1 + uint64(i)*h2) % m
+	}
+	return positions
+}
+
+// add 写入 key
+func (f bloomFilter) add(key string) {
+	for _, p := range f.positions(key) {
+		f.bits[p/8] |= 1 << (p % 8)
+	}
+}
+
+// contains 判断 key 是否可能已写入
+func (f bloomFilter) contains(key string) bool {
+	if len(f.bits) == 0 {
+		return false
+	}
+	for _, p := range f.positions(key) {
+		if f.bits[p/8]&(1<<(p%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// seenSet 用户已曝光的视频集合，由两代布隆过滤器组成：新视频写入当前一代，
+// 当前一代写满 capacity 个后成为上一代，原来的上一代被丢弃，因此只记住最近 capacity 到 2*capacity 个视频
+type seenSet struct {
+	current  bloomFilter
+	previous bloomFilter
+	count    int // 当前一代已写入的视频数
+	capacity int
+	rate     float64
+}
+
+// newSeenSet 按配置创建空的已曝光集合
+func newSeenSet() *seenSet {
+	cfg := config.AppConfig.Seen
+	capacity := cfg.Capacity
+	if capacity <= 0 {
+		capacity = defaultSeenCapacity
+	}
+	rate := cfg.FalsePositiveRate
+	if rate <= 0 || rate >= 1 {
+		rate = defaultSeenFalsePositiveRate
+	}
+	return &seenSet{current: newBloomFilter(capacity, rate), capacity: capacity, rate: rate}
+}
+
+// Contains 判断视频是否曝光过，可能误判为曝光过，不会漏判；nil 表示不过滤
+func (s *seenSet) Contains(awemeID string) bool {
+	if s == nil {
+		return false
+	}
+	return s.current.contains(awemeID) || s.previous.contains(awemeID)
+}
+
+// add 记录一个视频，已在当前一代中的不重复计数
+func (s *seenSet) add(awemeID string) {
+	if s.current.contains(awemeID) {
+		return
+	}
+	if s.count >= s.capacity {
+		s.previous = s.current
+		s.current = newBloomFilter(s.capacity, s.rate)
+		s.count = 0
+	}
+	s.current.add(awemeID)
+	s.count++
+}
+
+// loadSeenSet 读取用户的已曝光集合，没有记录或配置的容量、误判率已修改时返回空集合。
+// q 为事务时加行锁，用于随后写回
+func loadSeenSet(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, userID string, forUpdate bool) (*seenSet, error) {
+	s := newSeenSet()
+	query := `
+		SELECT hashes, current_bits, current_count, COALESCE(previous_bits, ''::bytea)
+		FROM user_seen_filters WHERE user_id = $1
+	`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var hashes, count int
+	var current, previous []byte
+	err := q.QueryRow(query, userID).Scan(&hashes, &current, &count, &previous)
+	if err == sql.ErrNoRows {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询已曝光视频失败: %v", err)
+	}
+	if hashes != s.current.hashes || len(current) != len(s.current.bits) {
+		return s, nil
+	}
+
+	s.current.bits = current
+	s.count = count
+	if len(previous) == len(current) {
+		s.previous = bloomFilter{bits: previous, hashes: hashes}
+	}
+	return s, nil
+}
+
+// getSeenSet 获取观看者的已曝光集合用于过滤列表，未登录时返回 nil 不过滤
+func getSeenSet(viewerID string) (*seenSet, error) {
+	if viewerID == "" {
+		return nil, nil
+	}
+	return loadSeenSet(config.DB, viewerID, false)
+}
+
+// MarkVideosSeenFromDB 将列表返回给用户的视频记入已曝光集合，之后推荐列表和长视频列表不再返回这些视频
+func MarkVideosSeenFromDB(userID string, videos []Video) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if userID == "" || len(videos) == 0 {
+		return nil
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	// 先插入空记录，保证并发请求在同一行上加锁，不会互相覆盖
+	_, err = tx.Exec(`
+		INSERT INTO user_seen_filters (user_id, hashes, current_bits, current_count)
+		VALUES ($1, 0, ''::bytea, 0)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("初始化已曝光视频失败: %v", err)
+	}
+	s, err := loadSeenSet(tx, userID, true)
+	if err != nil {
+		return err
+	}
+	for _, video := range videos {
+		s.add(video.AwemeID)
+	}
+
+	var previous []byte
+	if len(s.previous.bits) > 0 {
+		previous = s.previous.bits
+	}
+	_, err = tx.Exec(`
+		UPDATE user_seen_filters
+		SET hashes = $2, current_bits = $3, current_count = $4, previous_bits = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+	`, userID, s.current.hashes, s.current.bits, s.count, previous)
+	if err != nil {
+		return fmt.Errorf("保存已曝光视频失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// ResetSeenVideosFromDB 清空用户的已曝光集合，用于测试
+func ResetSeenVideosFromDB(userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if _, err := config.DB.Exec(`DELETE FROM user_seen_filters WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("清空已曝光视频失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewBloomFilterSize(t *testing.T) {
+	tests := []struct {
+		capacity   int
+		rate       float64
+		wantBytes  int
+		wantHashes int
+	}{
+		{5000, 0.01, 5991, 7},
+		{1000, 0.01, 1199, 7},
+		{100, 0.1, 60, 3},
+		{1, 0.5, 1, 6},
+	}
+	for _, tt := range tests {
+		f := newBloomFilter(tt.capacity, tt.rate)
+		if len(f.bits) != tt.wantBytes || f.hashes != tt.wantHashes {
+			t.Errorf("newBloomFilter(%d, %v) = %d bytes, %d hashes, want %d bytes, %d hashes",
+				tt.capacity, tt.rate, len(f.bits), f.hashes, tt.wantBytes, tt.wantHashes)
+		}
+	}
+}
+
+func TestBloomFilterContains(t *testing.T) {
+	const capacity, rate = 1000, 0.01
+	f := newBloomFilter(capacity, rate)
+	if f.contains("v0") {
+		t.Fatal("empty filter contains v0")
+	}
+	if (bloomFilter{}).contains("v0") {
+		t.Fatal("zero filter contains v0")
+	}
+
+	for i := 0; i < capacity; i++ {
+		f.add(fmt.Sprint("v", i))
+	}
+	for i := 0; i < capacity; i++ {
+		if !f.contains(fmt.Sprint("v", i)) {
+			t.Fatalf("filter lost v%d", i)
+		}
+	}
+
+	falsePositives := 0
+	const probes = 10000
+	for i := 0; i < probes; i++ {
+		if f.contains(fmt.Sprint("x", i)) {
+			falsePositives++
+		}
+	}
+	if got := float64(falsePositives) / probes; got > 2*rate {
+		t.Errorf("false positive rate = %v, want at most %v", got, 2*rate)
+	}
+}
+
+func TestSeenSetRotation(t *testing.T) {
+	s := &seenSet{current: newBloomFilter(3, 0.001), capacity: 3, rate: 0.001}
+
+	steps := []struct {
+		add         string
+		wantCount   int
+		wantSeen    []string
+		wantNotSeen []string
+	}{
+		{add: "a", wantCount: 1, wantSeen: []string{"a"}, wantNotSeen: []string{"b"}},
+		{add: "a", wantCount: 1, wantSeen: []string{"a"}},
+		{add: "b", wantCount: 2, wantSeen: []string{"a", "b"}},
+		{add: "c", wantCount: 3, wantSeen: []string{"a", "b", "c"}},
+		// 当前一代已满，a、b、c 成为上一代
+		{add: "d", wantCount: 1, wantSeen: []string{"a", "b", "c", "d"}},
+		{add: "e", wantCount: 2, wantSeen: []string{"a", "d", "e"}},
+		{add: "f", wantCount: 3, wantSeen: []string{"a", "f"}},
+		// 再次轮换，最早的一代被丢弃
+		{add: "g", wantCount: 1, wantSeen: []string{"d", "e", "f", "g"}, wantNotSeen: []string{"a", "b", "c"}},
+	}
+	for i, step := range steps {
+		s.add(step.add)
+		if s.count != step.wantCount {
+			t.Errorf("step %d add %s: count = %d, want %d", i, step.add, s.count, step.wantCount)
+		}
+		for _, id := range step.wantSeen {
+			if !s.Contains(id) {
+				t.Errorf("step %d add %s: %s not seen", i, step.add, id)
+			}
+		}
+		for _, id := range step.wantNotSeen {
+			if s.Contains(id) {
+				t.Errorf("step %d add %s: %s still seen", i, step.add, id)
+			}
+		}
+	}
+}
+
+func TestNilSeenSetContains(t *testing.T) {
+	var s *seenSet
+	if s.Contains("a") {
+		t.Error("nil seen set should not filter anything")
+	}
+}
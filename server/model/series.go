@@ -0,0 +1,342 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// 合集限制
+const (
+	MaxSeriesTitleLength = 50
+	MaxSeriesDescLength  = 500
+	MaxSeriesEpisodes    = 200
+)
+
+var (
+	// ErrSeriesNotFound 合集不存在
+	ErrSeriesNotFound = errors.New("合集不存在")
+	// ErrInvalidSeries 合集参数无效
+	ErrInvalidSeries = errors.New("合集参数无效")
+	// ErrSeriesForbidden 不是合集的作者
+	ErrSeriesForbidden = errors.New("无权操作该合集")
+)
+
+// Series 合集
+type Series struct {
+	SeriesID     string `json:"series_id"`
+	Title        string `json:"title"`
+	Desc         string `json:"desc"`
+	Cover        Cover  `json:"cover"`
+	AuthorUserID string `json:"author_user_id"`
+	EpisodeCount int    `json:"episode_count"` // 当前用户可见的集数
+	CreateTime   int64  `json:"create_time"`
+	UpdateTime   int64  `json:"update_time"`
+}
+
+// SeriesDetail 合集详情及按集数排序的视频
+type SeriesDetail struct {
+	Series
+	Episodes []Video `json:"episodes"`
+}
+
+// VideoSeries 视频所属合集，用于播放器自动播放下一集
+type VideoSeries struct {
+	SeriesID     string `json:"series_id"`
+	Title        string `json:"title"`
+	Episode      int    `json:"episode"`       // 当前视频是公开可见剧集中的第几集
+	EpisodeCount int    `json:"episode_count"` // 公开可见的集数
+	NextAwemeID  string `json:"next_aweme_id"` // 下一集公开视频，没有时为空
+}
+
+// SeriesCreateParams 创建合集参数
+type SeriesCreateParams struct {
+	Title    string   `json:"title" binding:"required"`
+	CoverURL string   `json:"cover_url"` // 为空时使用第一集的封面
+	Desc     string   `json:"desc"`
+	AwemeIDs []string `json:"aweme_ids"` // 按集数排列的视频
+}
+
+// validateSeries 校验合集标题、简介和封面
+func validateSeries(params *SeriesCreateParams, userID string) error {
+	params.Title = strings.TrimSpace(params.Title)
+	if params.Title == "" || utf8.RuneCountInString(params.Title) > MaxSeriesTitleLength {
+		return fmt.Errorf("%w: 标题不能为空且不超过 %d 个字符", ErrInvalidSeries, MaxSeriesTitleLength)
+	}
+	if utf8.RuneCountInString(params.Desc) > MaxSeriesDescLength {
+		return fmt.Errorf("%w: 简介不能超过 %d 个字符", ErrInvalidSeries, MaxSeriesDescLength)
+	}
+	if params.CoverURL != "" && !isUserMediaURL(params.CoverURL, userID) {
+		return fmt.Errorf("%w: 封面不是本人上传的", ErrInvalidSeries)
+	}
+	return nil
+}
+
+// setSeriesEpisodes 按 awemeIDs 的顺序覆盖设置合集剧集，视频必须是作者本人未删除的视频且不属于其他合集
+func setSeriesEpisodes(tx *sql.Tx, seriesPK int64, userID string, awemeIDs []string) error {
+	if len(awemeIDs) > MaxSeriesEpisodes {
+		return fmt.Errorf("%w: 合集最多 %d 集", ErrInvalidSeries, MaxSeriesEpisodes)
+	}
+
+	seen := make(map[string]bool)
+	videoIDs := make([]int64, 0, len(awemeIDs))
+	for _, awemeID := range awemeIDs {
+		if seen[awemeID] {
+			return fmt.Errorf("%w: 视频 %s 重复", ErrInvalidSeries, awemeID)
+		}
+		seen[awemeID] = true
+
+		videoID, err := getOwnedVideoID(tx, awemeID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVideoNotDeleted(tx, videoID); err != nil {
+			return err
+		}
+
+		var inOther bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM series_videos WHERE video_id = $1 AND series_id <> $2)
+		`, videoID, seriesPK).Scan(&inOther)
+		if err != nil {
+			return fmt.Errorf("查询视频合集失败: %v", err)
+		}
+		if inOther {
+			return fmt.Errorf("%w: 视频 %s 已在其他合集中", ErrInvalidSeries, awemeID)
+		}
+		videoIDs = append(videoIDs, videoID)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM series_videos WHERE series_id = $1`, seriesPK); err != nil {
+		return fmt.Errorf("清除合集剧集失败: %v", err)
+	}
+	for i, videoID := range videoIDs {
+		_, err := tx.Exec(`
+			INSERT INTO series_videos (series_id, video_id, episode) VALUES ($1, $2, $3)
+		`, seriesPK, videoID, i+1)
+		if err != nil {
+			return fmt.Errorf("保存合集剧集失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// CreateSeriesFromDB 创建合集
+func CreateSeriesFromDB(userID string, params SeriesCreateParams) (SeriesDetail, error) {
+	if config.DB == nil {
+		return SeriesDetail{}, fmt.Errorf("数据库未初始化")
+	}
+	if err := validateSeries(&params, userID); err != nil {
+		return SeriesDetail{}, err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return SeriesDetail{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	seriesID := newAwemeID()
+	var seriesPK int64
+	err = tx.QueryRow(`
+		INSERT INTO series (series_id, author_user_id, title, cover_url, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, seriesID, userID, params.Title, params.CoverURL, params.Desc).Scan(&seriesPK)
+	if err != nil {
+		return SeriesDetail{}, fmt.Errorf("保存合集失败: %v", err)
+	}
+
+	if err := setSeriesEpisodes(tx, seriesPK, userID, params.AwemeIDs); err != nil {
+		return SeriesDetail{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SeriesDetail{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return GetSeriesFromDB(userID, seriesID)
+}
+
+// UpdateSeriesEpisodesFromDB 调整合集的剧集和顺序，awemeIDs 为新的完整剧集列表
+func UpdateSeriesEpisodesFromDB(seriesID, userID string, awemeIDs []string) (SeriesDetail, error) {
+	if config.DB == nil {
+		return SeriesDetail{}, fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return SeriesDetail{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	var seriesPK int64
+	var authorUserID string
+	err = tx.QueryRow(`
+		SELECT id, COALESCE(author_user_id, '') FROM series WHERE series_id = $1 FOR UPDATE
+	`, seriesID).Scan(&seriesPK, &authorUserID)
+	if err == sql.ErrNoRows {
+		return SeriesDetail{}, ErrSeriesNotFound
+	}
+	if err != nil {
+		return SeriesDetail{}, fmt.Errorf("查询合集失败: %v", err)
+	}
+	if authorUserID != userID {
+		return SeriesDetail{}, ErrSeriesForbidden
+	}
+
+	if err := setSeriesEpisodes(tx, seriesPK, userID, awemeIDs); err != nil {
+		return SeriesDetail{}, err
+	}
+	_, err = tx.Exec(`UPDATE series SET updated_at = CURRENT_TIMESTAMP WHERE id = $1`, seriesPK)
+	if err != nil {
+		return SeriesDetail{}, fmt.Errorf("更新合集失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SeriesDetail{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return GetSeriesFromDB(userID, seriesID)
+}
+
+// GetSeriesFromDB 获取合集详情，剧集只包含当前用户可见的视频
+func GetSeriesFromDB(viewerID, seriesID string) (SeriesDetail, error) {
+	if config.DB == nil {
+		return SeriesDetail{}, fmt.Errorf("数据库未初始化")
+	}
+
+	var detail SeriesDetail
+	var seriesPK int64
+	var coverURL string
+	var createdAt, updatedAt time.Time
+	err := config.DB.QueryRow(`
+		SELECT s.id, s.series_id, s.title, COALESCE(s.description, ''), COALESCE(s.author_user_id, ''),
+		       COALESCE(NULLIF(s.cover_url, ''), (
+		           SELECT vc.url FROM series_videos sv
+		           JOIN videos v ON v.id = sv.video_id
+		           JOIN video_covers vc ON vc.video_id = sv.video_id
+		           WHERE sv.series_id = s.id AND `+videoPublicCondition()+`
+		           ORDER BY sv.episode, vc.id LIMIT 1
+		       ), ''),
+		       s.created_at, s.updated_at
+		FROM series s
+		WHERE s.series_id = $1
+	`, seriesID).Scan(&seriesPK, &detail.SeriesID, &detail.Title, &detail.Desc, &detail.AuthorUserID,
+		&coverURL, &createdAt, &updatedAt)
+	if err == sql.ErrNoRows {
+		return SeriesDetail{}, ErrSeriesNotFound
+	}
+	if err != nil {
+		return SeriesDetail{}, fmt.Errorf("查询合集失败: %v", err)
+	}
+	detail.Cover = Cover{URI: mediaURI(coverURL), URLList: []string{coverURL}}
+	detail.CreateTime = createdAt.Unix()
+	detail.UpdateTime = updatedAt.Unix()
+
+	query := `
+		SELECT ` + videoListColumns + `
+		FROM videos v
+		JOIN series_videos sv ON sv.video_id = v.id
+		` + videoListJoins + `
+		WHERE sv.series_id = $1 AND ` + videoVisibleCondition("$2") + `
+		ORDER BY sv.episode
+	`
+	detail.Episodes, err = queryVideoList(query, seriesPK, viewerID)
+	if err != nil {
+		return SeriesDetail{}, err
+	}
+	detail.EpisodeCount = len(detail.Episodes)
+	return detail, nil
+}
+
+// GetNextEpisodeFromDB 获取合集中当前视频之后第一个对当前用户可见的视频，已是最后一集时返回 nil
+func GetNextEpisodeFromDB(viewerID, awemeID string) (*Video, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	var visible bool
+	err := config.DB.QueryRow(`
+		SELECT `+videoVisibleCondition("$2")+` FROM videos v WHERE v.aweme_id = $1
+	`, awemeID, viewerID).Scan(&visible)
+	if err == sql.ErrNoRows || (err == nil && !visible) {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询视频失败: %v", err)
+	}
+
+	query := `
+		SELECT ` + videoListColumns + `
+		FROM videos v
+		JOIN series_videos sv ON sv.video_id = v.id
+		JOIN series_videos cur ON cur.series_id = sv.series_id AND sv.episode > cur.episode
+		JOIN videos cv ON cv.id = cur.video_id
+		` + videoListJoins + `
+		WHERE cv.aweme_id = $1 AND ` + videoVisibleCondition("$2") + `
+		ORDER BY sv.episode
+		LIMIT 1
+	`
+	videos, err := queryVideoList(query, awemeID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(videos) == 0 {
+		return nil, nil
+	}
+	return &videos[0], nil
+}
+
+// fillVideoSeries 填充视频列表所属的合集，第几集、总集数和下一集都只统计公开可见的视频
+func fillVideoSeries(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT cv.aweme_id, s.series_id, s.title,
+		       (SELECT COUNT(*) FROM series_videos sv JOIN videos v ON sv.video_id = v.id
+		        WHERE sv.series_id = s.id AND sv.episode <= cur.episode AND `+videoPublicCondition()+`),
+		       (SELECT COUNT(*) FROM series_videos sv JOIN videos v ON sv.video_id = v.id
+		        WHERE sv.series_id = s.id AND `+videoPublicCondition()+`),
+		       COALESCE((SELECT v.aweme_id FROM series_videos sv JOIN videos v ON sv.video_id = v.id
+		        WHERE sv.series_id = s.id AND sv.episode > cur.episode AND `+videoPublicCondition()+`
+		        ORDER BY sv.episode LIMIT 1), '')
+		FROM series_videos cur
+		JOIN series s ON cur.series_id = s.id
+		JOIN videos cv ON cur.video_id = cv.id
+		WHERE cv.aweme_id = ANY($1)
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频合集失败: %v", err)
+	}
+	defer rows.Close()
+
+	seriesByVideo := make(map[string]*VideoSeries)
+	for rows.Next() {
+		var awemeID string
+		var series VideoSeries
+		err := rows.Scan(&awemeID, &series.SeriesID, &series.Title, &series.Episode,
+			&series.EpisodeCount, &series.NextAwemeID)
+		if err != nil {
+			return fmt.Errorf("解析视频合集失败: %v", err)
+		}
+		seriesByVideo[awemeID] = &series
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频合集时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		if series, ok := seriesByVideo[videos[i].AwemeID]; ok {
+			videos[i].Series = series
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package model
+
+import (
+	"fmt"
+	"klik/server/config"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 相似视频默认参数，可在配置文件 similarity 节中覆盖
+const (
+	defaultSimilarityWindowDays     = 90  // 只统计最近一段时间内的互动
+	defaultSimilarityNeighbors      = 20  // 每个视频保存的相似视频数
+	defaultSimilarityMinCommonUsers = 2   // 两个视频至少有多少个共同互动用户才计算相似度
+	defaultSimilarityMaxUserItems   = 200 // 每个用户最多参与计算的视频数，避免重度用户的组合数过多
+	similarSeedLimit                = 20  // 推荐时取观看者最近喜欢、收藏的视频数作为种子
+)
+
+// RecommendReasonSimilar 推荐理由：与观看者喜欢或收藏过的视频相似
+const RecommendReasonSimilar = "similar"
+
+// RecommendReason 推荐理由，如 “因为你喜欢过 X”
+type RecommendReason struct {
+	Type    string `json:"type"`     // similar
+	AwemeID string `json:"aweme_id"` // 观看者喜欢或收藏过的视频
+	Desc    string `json:"desc"`
+}
+
+// videoInteraction 用户与视频的互动，Weight 取各种互动中最强的一种
+type videoInteraction struct {
+	UserID  string
+	VideoID int64
+	Weight  float64
+}
+
+// videoNeighbor 相似视频
+type videoNeighbor struct {
+	VideoID     int64
+	Score       float64
+	CommonUsers int
+}
+
+// computeVideoNeighbors 按共同互动计算视频之间的余弦相似度，返回每个视频最相似的 topN 个视频。
+// interactions 需按用户分组排列，每个用户只取前 maxUserItems 个视频
+func computeVideoNeighbors(interactions []videoInteraction, topN, minCommonUsers, maxUserItems int) map[int64][]videoNeighbor {
+	type pair struct{ a, b int64 }
+	norms := make(map[int64]float64)
+	dots := make(map[pair]float64)
+	commons := make(map[pair]int)
+
+	for start := 0; start < len(interactions); {
+		end := start
+		for end < len(interactions) && interactions[end].UserID == interactions[start].UserID {
+			end++
+		}
+		items := interactions[start:end]
+		if len(items) > maxUserItems {
+			items = items[:maxUserItems]
+		}
+		for i, x := range items {
+			norms[x.VideoID] += x.Weight * x.Weight
+			for _, y := range items[i+1:] {
+				p := pair{x.VideoID, y.VideoID}
+				if p.a > p.b {
+					p.a, p.b = p.b, p.a
+				}
+				dots[p] += x.Weight * y.Weight
+				commons[p]++
+			}
+		}
+		start = end
+	}
+
+	neighbors := make(map[int64][]videoNeighbor)
+	for p, dot := range dots {
+		if commons[p] < minCommonUsers || p.a == p.b {
+			continue
+		}
+		score := dot / math.Sqrt(norms[p.a]*norms[p.b])
+		neighbors[p.a] = append(neighbors[p.a], videoNeighbor{VideoID: p.b, Score: score, CommonUsers: commons[p]})
+		neighbors[p.b] = append(neighbors[p.b], videoNeighbor{VideoID: p.a, Score: score, CommonUsers: commons[p]})
+	}
+	for videoID, list := range neighbors {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Score != list[j].Score {
+				return list[i].Score > list[j].Score
+			}
+			return list[i].VideoID > list[j].VideoID
+		})
+		if len(list) > topN {
+			list = list[:topN]
+		}
+		neighbors[videoID] = list
+	}
+	return neighbors
+}
+
+// getVideoInteractions 读取时间窗口内的观看、收藏和点赞记录，按用户分组，同一用户的视频按互动强度排列
+func getVideoInteractions(since time.Time) ([]videoInteraction, error) {
+	rows, err := config.DB.Query(`
+		SELECT e.commenter_id, e.video_id, MAX(e.weight)
+		FROM (
+			SELECT commenter_id, video_id, 1.0::float8 AS weight FROM user_history_videos WHERE view_time >= $1
+			UNION ALL
+			SELECT commenter_id, video_id, 2.0::float8 FROM user_collect_videos WHERE created_at >= $1
+			UNION ALL
+			SELECT commenter_id, video_id, 3.0::float8 FROM user_like_videos WHERE created_at >= $1
+		) e
+		WHERE e.commenter_id IS NOT NULL AND e.video_id IS NOT NULL
+		GROUP BY e.commenter_id, e.video_id
+		ORDER BY e.commenter_id, MAX(e.weight) DESC, e.video_id DESC
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户互动失败: %v", err)
+	}
+	defer rows.Close()
+
+	var interactions []videoInteraction
+	for rows.Next() {
+		var x videoInteraction
+		if err := rows.Scan(&x.UserID, &x.VideoID, &x.Weight); err != nil {
+			return nil, fmt.Errorf("解析用户互动失败: %v", err)
+		}
+		interactions = append(interactions, x)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询用户互动时发生错误: %v", err)
+	}
+	return interactions, nil
+}
+
+// RefreshVideoNeighborsFromDB 重新计算所有视频的相似视频并整体替换，返回有相似视频的视频数
+func RefreshVideoNeighborsFromDB() (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	cfg := config.AppConfig.Similarity
+	windowDays := cfg.WindowDays
+	if windowDays <= 0 {
+		windowDays = defaultSimilarityWindowDays
+	}
+	topN := cfg.Neighbors
+	if topN <= 0 {
+		topN = defaultSimilarityNeighbors
+	}
+	minCommonUsers := cfg.MinCommonUsers
+	if minCommonUsers <= 0 {
+		minCommonUsers = defaultSimilarityMinCommonUsers
+	}
+	maxUserItems := cfg.MaxUserItems
+	if maxUserItems <= 0 {
+		maxUserItems = defaultSimilarityMaxUserItems
+	}
+
+	interactions, err := getVideoInteractions(time.Now().AddDate(0, 0, -windowDays))
+	if err != nil {
+		return 0, err
+	}
+	neighbors := computeVideoNeighbors(interactions, topN, minCommonUsers, maxUserItems)
+
+	var videoIDs, neighborIDs []int64
+	var scores []float64
+	var commons []int64
+	for videoID, list := range neighbors {
+		for _, n := range list {
+			videoIDs = append(videoIDs, videoID)
+			neighborIDs = append(neighborIDs, n.VideoID)
+			scores = append(scores, n.Score)
+			commons = append(commons, int64(n.CommonUsers))
+		}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM video_neighbors`); err != nil {
+		return 0, fmt.Errorf("清除相似视频失败: %v", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO video_neighbors (video_id, neighbor_id, score, common_users)
+		SELECT * FROM unnest($1::int[], $2::int[], $3::float8[], $4::int[])
+	`, pq.Array(videoIDs), pq.Array(neighborIDs), pq.Array(scores), pq.Array(commons))
+	if err != nil {
+		return 0, fmt.Errorf("保存相似视频失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return len(neighbors), nil
+}
+
+// GetRelatedVideosFromDB 获取与视频相似的视频，按相似度排列，只返回对观看者可见的视频
+func GetRelatedVideosFromDB(awemeID, viewerID string, limit int) ([]Video, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	videoID, err := getVisibleVideoID(config.DB, awemeID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + videoListColumns + `
+		FROM video_neighbors n
+		JOIN videos v ON v.id = n.neighbor_id
+		` + videoListJoins + `
+		WHERE n.video_id = $1 AND ` + videoVisibleCondition("$2") + `
+		ORDER BY n.score DESC, v.id DESC
+		LIMIT $3
+	`
+	return queryVideoList(query, videoID, viewerID, limit)
+}
+
+// similarCandidates 召回与观看者最近喜欢、收藏的视频相似的视频，记录相似度最高的种子视频作为推荐理由
+func similarCandidates(req rankRequest, limit int) ([]rankCandidate, error) {
+	query := `
+		SELECT * FROM (
+			SELECT DISTINCT ON (v.id) ` + rankCandidateColumns + `, n.score AS similarity, seed.aweme_id
+			FROM (
+				SELECT s.video_id, MAX(s.created_at) AS created_at
+				FROM (
+					SELECT video_id, created_at FROM user_like_videos WHERE commenter_id = $1
+					UNION ALL
+					SELECT video_id, created_at FROM user_collect_videos WHERE commenter_id = $1
+				) s
+				WHERE s.created_at < to_timestamp($3)
+				GROUP BY s.video_id
+				ORDER BY MAX(s.created_at) DESC
+				LIMIT $4
+			) seeds
+			JOIN videos seed ON seed.id = seeds.video_id
+			JOIN video_neighbors n ON n.video_id = seeds.video_id
+			JOIN videos v ON v.id = n.neighbor_id
+			LEFT JOIN video_statistics vs ON v.id = vs.video_id
+			WHERE ` + rankCandidateFilter("$1", "$3") + `
+			  AND NOT EXISTS (SELECT 1 FROM user_like_videos l WHERE l.video_id = v.id AND l.commenter_id = $1)
+			  AND NOT EXISTS (SELECT 1 FROM user_collect_videos cv WHERE cv.video_id = v.id AND cv.commenter_id = $1)
+			ORDER BY v.id, n.score DESC
+		) c
+		ORDER BY c.similarity DESC
+		LIMIT $2
+	`
+	rows, err := config.DB.Query(query, req.ViewerID, limit, req.Now.Unix(), similarSeedLimit)
+	if err != nil {
+		return nil, fmt.Errorf("查询候选视频失败: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []rankCandidate
+	for rows.Next() {
+		var c rankCandidate
+		if err := scanRankCandidate(rows, &c, &c.Similarity, &c.SeedAwemeID); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询候选视频时发生错误: %v", err)
+	}
+	return candidates, nil
+}
+
+// similarityScore 相似视频召回的候选按相似度加分，其他候选不变
+func similarityScore(req rankRequest, c *rankCandidate) float64 {
+	return 1 + c.Similarity
+}
+
+// fillRecommendReasons 为相似视频召回的视频填充推荐理由，candidates 为当前页对应的候选。
+// 种子视频已对观看者不可见（设为私密、删除或违规）时不返回推荐理由
+func fillRecommendReasons(videos []Video, candidates []rankCandidate, viewerID string) error {
+	seeds := make(map[string]string)
+	var seedIDs []string
+	for _, c := range candidates {
+		if c.SeedAwemeID != "" {
+			seeds[c.AwemeID] = c.SeedAwemeID
+			seedIDs = append(seedIDs, c.SeedAwemeID)
+		}
+	}
+	if len(seedIDs) == 0 {
+		return nil
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, COALESCE(v.video_desc, '') FROM videos v
+		WHERE v.aweme_id = ANY($1) AND `+videoVisibleCondition("$2"),
+		pq.Array(seedIDs), viewerID)
+	if err != nil {
+		return fmt.Errorf("查询推荐理由失败: %v", err)
+	}
+	defer rows.Close()
+
+	descs := make(map[string]string)
+	for rows.Next() {
+		var awemeID, desc string
+		if err := rows.Scan(&awemeID, &desc); err != nil {
+			return fmt.Errorf("解析推荐理由失败: %v", err)
+		}
+		descs[awemeID] = desc
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询推荐理由时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		seed, ok := seeds[videos[i].AwemeID]
+		if !ok {
+			continue
+		}
+		if desc, visible := descs[seed]; visible {
+			videos[i].RecommendReason = &RecommendReason{Type: RecommendReasonSimilar, AwemeID: seed, Desc: desc}
+		}
+	}
+	return nil
+}
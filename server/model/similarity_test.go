@@ -0,0 +1,62 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeVideoNeighbors(t *testing.T) {
+	// u1、u2 都看过 1 和 2，u3 看过 1 和 3，u4 的视频数超过上限只取前两个
+	interactions := []videoInteraction{
+		{UserID: "u1", VideoID: 1, Weight: 3}, {UserID: "u1", VideoID: 2, Weight: 3},
+		{UserID: "u2", VideoID: 1, Weight: 1}, {UserID: "u2", VideoID: 2, Weight: 1},
+		{UserID: "u3", VideoID: 1, Weight: 2}, {UserID: "u3", VideoID: 3, Weight: 2},
+		{UserID: "u4", VideoID: 4, Weight: 1}, {UserID: "u4", VideoID: 5, Weight: 1}, {UserID: "u4", VideoID: 1, Weight: 1},
+	}
+
+	tests := []struct {
+		name           string
+		minCommonUsers int
+		maxUserItems   int
+		videoID        int64
+		wantNeighbors  []int64
+		wantScore      float64 // 第一个相似视频的得分
+	}{
+		{
+			name: "min common users filters weak pairs", minCommonUsers: 2, maxUserItems: 2,
+			videoID: 1, wantNeighbors: []int64{2},
+			// dot = 3*3 + 1*1 = 10，|1|² = 9+1+4 = 14，|2|² = 9+1 = 10
+			wantScore: 10 / math.Sqrt(14*10),
+		},
+		{
+			name: "single common user allowed", minCommonUsers: 1, maxUserItems: 2,
+			videoID: 1, wantNeighbors: []int64{2, 3},
+			wantScore: 10 / math.Sqrt(14*10),
+		},
+		{
+			name: "max user items truncates heavy users", minCommonUsers: 1, maxUserItems: 2,
+			videoID: 4, wantNeighbors: []int64{5},
+			wantScore: 1,
+		},
+		{
+			name: "top n", minCommonUsers: 1, maxUserItems: 3,
+			videoID: 1, wantNeighbors: []int64{2, 3},
+		},
+	}
+	for _, tt := range tests {
+		neighbors := computeVideoNeighbors(interactions, 2, tt.minCommonUsers, tt.maxUserItems)
+		got := neighbors[tt.videoID]
+		if len(got) != len(tt.wantNeighbors) {
+			t.Errorf("%s: video %d has %d neighbors %+v, want %v", tt.name, tt.videoID, len(got), got, tt.wantNeighbors)
+			continue
+		}
+		for i, id := range tt.wantNeighbors {
+			if got[i].VideoID != id {
+				t.Errorf("%s: neighbor %d = %d, want %d", tt.name, i, got[i].VideoID, id)
+			}
+		}
+		if tt.wantScore != 0 && math.Abs(got[0].Score-tt.wantScore) > 1e-9 {
+			t.Errorf("%s: score = %v, want %v", tt.name, got[0].Score, tt.wantScore)
+		}
+	}
+}
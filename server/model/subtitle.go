@@ -0,0 +1,199 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// MaxSubtitleLabelLength 字幕名称最大字符数
+const MaxSubtitleLabelLength = 50
+
+// SubtitleFormatWebVTT 字幕文件格式，上传的 SRT 会转换为 WebVTT
+const SubtitleFormatWebVTT = "webvtt"
+
+var (
+	// ErrInvalidSubtitle 字幕参数无效
+	ErrInvalidSubtitle = errors.New("字幕参数无效")
+	// ErrSubtitleNotFound 字幕不存在
+	ErrSubtitleNotFound = errors.New("字幕不存在")
+)
+
+// subtitleLanguagePattern 语言代码，如 zh、zh-CN、zh-Hans-CN
+var subtitleLanguagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// SubtitleTrack 字幕轨道
+type SubtitleTrack struct {
+	Language string   `json:"language"`
+	Label    string   `json:"label"`
+	Format   string   `json:"format"`
+	URI      string   `json:"uri"`
+	URLList  []string `json:"url_list"`
+}
+
+// NormalizeSubtitleLanguage 校验语言代码并统一大小写：语言小写，地区大写，书写系统首字母大写
+func NormalizeSubtitleLanguage(language string) (string, error) {
+	language = strings.TrimSpace(strings.ReplaceAll(language, "_", "-"))
+	if len(language) > 20 || !subtitleLanguagePattern.MatchString(language) {
+		return "", fmt.Errorf("%w: 语言代码无效", ErrInvalidSubtitle)
+	}
+
+	tags := strings.Split(language, "-")
+	for i, tag := range tags {
+		switch {
+		case i == 0:
+			tags[i] = strings.ToLower(tag)
+		case len(tag) == 2:
+			tags[i] = strings.ToUpper(tag)
+		case len(tag) == 4:
+			tags[i] = strings.ToUpper(tag[:1]) + strings.ToLower(tag[1:])
+		default:
+			tags[i] = strings.ToLower(tag)
+		}
+	}
+	return strings.Join(tags, "-"), nil
+}
+
+// SaveVideoSubtitleFromDB 保存视频某种语言的字幕，已有该语言字幕时替换并清理旧文件
+func SaveVideoSubtitleFromDB(awemeID, userID, language, label, url string) (SubtitleTrack, error) {
+	if config.DB == nil {
+		return SubtitleTrack{}, fmt.Errorf("数据库未初始化")
+	}
+	language, err := NormalizeSubtitleLanguage(language)
+	if err != nil {
+		return SubtitleTrack{}, err
+	}
+	label = strings.TrimSpace(label)
+	if label == "" {
+		label = language
+	}
+	if utf8.RuneCountInString(label) > MaxSubtitleLabelLength {
+		return SubtitleTrack{}, fmt.Errorf("%w: 名称不能超过 %d 个字符", ErrInvalidSubtitle, MaxSubtitleLabelLength)
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return SubtitleTrack{}, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return SubtitleTrack{}, err
+	}
+	if err := checkVideoNotDeleted(tx, videoID); err != nil {
+		return SubtitleTrack{}, err
+	}
+
+	var oldURL string
+	err = tx.QueryRow(`
+		SELECT COALESCE(url, '') FROM video_subtitles WHERE video_id = $1 AND language = $2 FOR UPDATE
+	`, videoID, language).Scan(&oldURL)
+	if err != nil && err != sql.ErrNoRows {
+		return SubtitleTrack{}, fmt.Errorf("查询字幕失败: %v", err)
+	}
+
+	track := SubtitleTrack{
+		Language: language,
+		Label:    label,
+		Format:   SubtitleFormatWebVTT,
+		URI:      mediaURI(url),
+		URLList:  []string{url},
+	}
+	_, err = tx.Exec(`
+		INSERT INTO video_subtitles (video_id, language, label, uri, url)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (video_id, language) DO UPDATE
+		SET label = EXCLUDED.label, uri = EXCLUDED.uri, url = EXCLUDED.url, updated_at = CURRENT_TIMESTAMP
+	`, videoID, language, label, track.URI, url)
+	if err != nil {
+		return SubtitleTrack{}, fmt.Errorf("保存字幕失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SubtitleTrack{}, fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	if oldURL != "" && oldURL != url {
+		removeUnreferencedMedia([]string{oldURL})
+	}
+	return track, nil
+}
+
+// DeleteVideoSubtitleFromDB 删除视频某种语言的字幕及其文件
+func DeleteVideoSubtitleFromDB(awemeID, userID, language string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	language, err := NormalizeSubtitleLanguage(language)
+	if err != nil {
+		return err
+	}
+
+	videoID, err := getOwnedVideoID(config.DB, awemeID, userID)
+	if err != nil {
+		return err
+	}
+
+	var url string
+	err = config.DB.QueryRow(`
+		DELETE FROM video_subtitles WHERE video_id = $1 AND language = $2 RETURNING COALESCE(url, '')
+	`, videoID, language).Scan(&url)
+	if err == sql.ErrNoRows {
+		return ErrSubtitleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("删除字幕失败: %v", err)
+	}
+
+	removeUnreferencedMedia([]string{url})
+	return nil
+}
+
+// fillVideoSubtitles 填充视频列表的字幕轨道
+func fillVideoSubtitles(videos []Video) error {
+	awemeIDs := make([]string, len(videos))
+	for i, video := range videos {
+		awemeIDs[i] = video.AwemeID
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id, vs.language, COALESCE(vs.label, vs.language), COALESCE(vs.uri, ''), COALESCE(vs.url, '')
+		FROM video_subtitles vs
+		JOIN videos v ON vs.video_id = v.id
+		WHERE v.aweme_id = ANY($1)
+		ORDER BY vs.id
+	`, pq.Array(awemeIDs))
+	if err != nil {
+		return fmt.Errorf("查询视频字幕失败: %v", err)
+	}
+	defer rows.Close()
+
+	tracks := make(map[string][]SubtitleTrack)
+	for rows.Next() {
+		var awemeID, url string
+		track := SubtitleTrack{Format: SubtitleFormatWebVTT}
+		if err := rows.Scan(&awemeID, &track.Language, &track.Label, &track.URI, &url); err != nil {
+			return fmt.Errorf("解析视频字幕失败: %v", err)
+		}
+		track.URLList = []string{url}
+		tracks[awemeID] = append(tracks[awemeID], track)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询视频字幕时发生错误: %v", err)
+	}
+
+	for i := range videos {
+		videos[i].VideoInfo.Subtitles = tracks[videos[i].AwemeID]
+		if videos[i].VideoInfo.Subtitles == nil {
+			videos[i].VideoInfo.Subtitles = []SubtitleTrack{}
+		}
+	}
+	return nil
+}
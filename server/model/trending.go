@@ -0,0 +1,540 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// 热榜条目类型，对应 trending_entries.kind
+const (
+	TrendingKindVideo   = "video"
+	TrendingKindHashtag = "hashtag"
+)
+
+// 热榜人工干预操作，对应 trending_overrides.action
+const (
+	TrendingActionPin    = "pin"    // 固定在指定位置
+	TrendingActionRemove = "remove" // 移出热榜
+)
+
+// 排名变化，对应 TrendingRank.Trend
+const (
+	TrendingNew  = "new"  // 新上榜
+	TrendingUp   = "up"   // 上升
+	TrendingDown = "down" // 下降
+	TrendingSame = "same" // 持平
+)
+
+// 热榜默认参数，可在配置文件 trending 节中覆盖
+const (
+	defaultTrendingWindowHours   = 2  // 统计互动速度的时间窗口（小时）
+	defaultTrendingSize          = 50 // 每个榜单的条目数
+	defaultTrendingRetentionDays = 7  // 快照保留天数
+)
+
+// ErrInvalidTrending 热榜参数无效
+var ErrInvalidTrending = errors.New("热榜参数无效")
+
+// trendingRefreshMu 保证同一时间只有一次热榜计算，避免定时任务和人工干预同时写入快照
+var trendingRefreshMu sync.Mutex
+
+// trendingEventsQuery 时间窗口内的互动事件，每行为 (video_id, weight)，$1 为窗口开始时间。
+// 观看、点赞、评论、收藏的权重依次增加
+const trendingEventsQuery = `
+	SELECT video_id, 1.0::float8 AS weight FROM user_history_videos WHERE view_time >= $1
+	UNION ALL
+	SELECT video_id, 2.0::float8 FROM user_like_videos WHERE created_at >= $1
+	UNION ALL
+	SELECT video_id, 3.0::float8 FROM comments WHERE video_id IS NOT NULL AND created_at >= $1
+	UNION ALL
+	SELECT video_id, 3.0::float8 FROM user_collect_videos WHERE created_at >= $1`
+
+// TrendingRank 热榜条目的排名信息
+type TrendingRank struct {
+	Rank     int     `json:"rank"`
+	Trend    string  `json:"trend"`     // new、up、down、same
+	Change   int     `json:"change"`    // 与上一次计算相比的排名变化，上升为正
+	PeakRank int     `json:"peak_rank"` // 快照保留期内的最高排名
+	Score    float64 `json:"score"`     // 每小时加权互动数
+	IsPinned bool    `json:"is_pinned"` // 是否由管理员固定
+}
+
+// TrendingVideo 视频热榜条目
+type TrendingVideo struct {
+	TrendingRank
+	Video Video `json:"video"`
+}
+
+// TrendingHashtag 话题热榜条目
+type TrendingHashtag struct {
+	TrendingRank
+	Hashtag Hashtag `json:"hashtag"`
+}
+
+// Trending 热榜
+type Trending struct {
+	UpdatedAt int64             `json:"updated_at"` // 计算时间（Unix 秒），还没有计算过时为 0
+	Videos    []TrendingVideo   `json:"videos"`
+	Hashtags  []TrendingHashtag `json:"hashtags"`
+}
+
+// trendingItem 计算中的热榜条目
+type trendingItem struct {
+	TargetID int64
+	Score    float64
+	IsPinned bool
+}
+
+// trendingOverride 管理员对热榜的干预
+type trendingOverride struct {
+	Kind     string
+	TargetID int64
+	Action   string
+	Position int
+}
+
+// trendingSize 每个榜单的条目数
+func trendingSize() int {
+	if size := config.AppConfig.Trending.Size; size > 0 {
+		return size
+	}
+	return defaultTrendingSize
+}
+
+// queryTrendingItems 执行热榜计算查询，每行为 (target_id, score)
+func queryTrendingItems(query string, args ...interface{}) ([]trendingItem, error) {
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("计算热榜失败: %v", err)
+	}
+	defer rows.Close()
+
+	var items []trendingItem
+	for rows.Next() {
+		var item trendingItem
+		if err := rows.Scan(&item.TargetID, &item.Score); err != nil {
+			return nil, fmt.Errorf("解析热榜数据失败: %v", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("计算热榜时发生错误: %v", err)
+	}
+	return items, nil
+}
+
+// computeTrendingVideos 按时间窗口内每小时加权互动数计算视频热榜，只包含公开视频
+func computeTrendingVideos(since time.Time, windowHours float64, size int) ([]trendingItem, error) {
+	query := `
+		SELECT v.id, SUM(e.weight) / $3::float8
+		FROM (` + trendingEventsQuery + `) e
+		JOIN videos v ON v.id = e.video_id
+		WHERE ` + videoPublicCondition() + `
+		GROUP BY v.id
+		ORDER BY 2 DESC, v.id DESC
+		LIMIT $2
+	`
+	return queryTrendingItems(query, since, size, windowHours)
+}
+
+// computeTrendingHashtags 按话题下公开视频的互动速度之和计算话题热榜
+func computeTrendingHashtags(since time.Time, windowHours float64, size int) ([]trendingItem, error) {
+	query := `
+		SELECT vh.hashtag_id, SUM(e.weight) / $3::float8
+		FROM (` + trendingEventsQuery + `) e
+		JOIN videos v ON v.id = e.video_id
+		JOIN (SELECT DISTINCT video_id, hashtag_id FROM video_hashtags) vh ON vh.video_id = v.id
+		WHERE ` + videoPublicCondition() + `
+		GROUP BY vh.hashtag_id
+		ORDER BY 2 DESC, vh.hashtag_id DESC
+		LIMIT $2
+	`
+	return queryTrendingItems(query, since, size, windowHours)
+}
+
+// getTrendingOverrides 获取管理员对热榜的全部干预
+func getTrendingOverrides() ([]trendingOverride, error) {
+	rows, err := config.DB.Query(`SELECT kind, target_id, action, COALESCE(position, 1) FROM trending_overrides`)
+	if err != nil {
+		return nil, fmt.Errorf("查询热榜干预失败: %v", err)
+	}
+	defer rows.Close()
+
+	var overrides []trendingOverride
+	for rows.Next() {
+		var o trendingOverride
+		if err := rows.Scan(&o.Kind, &o.TargetID, &o.Action, &o.Position); err != nil {
+			return nil, fmt.Errorf("解析热榜干预失败: %v", err)
+		}
+		overrides = append(overrides, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询热榜干预时发生错误: %v", err)
+	}
+	return overrides, nil
+}
+
+// applyTrendingOverrides 应用管理员干预：移除被移出的条目，再按位置从小到大插入固定的条目，最后截取榜单长度
+func applyTrendingOverrides(items []trendingItem, overrides []trendingOverride, kind string, size int) []trendingItem {
+	skip := make(map[int64]bool)
+	var pins []trendingOverride
+	for _, o := range overrides {
+		if o.Kind != kind {
+			continue
+		}
+		skip[o.TargetID] = true
+		if o.Action == TrendingActionPin {
+			pins = append(pins, o)
+		}
+	}
+	sort.SliceStable(pins, func(i, j int) bool { return pins[i].Position < pins[j].Position })
+
+	result := make([]trendingItem, 0, len(items)+len(pins))
+	for _, item := range items {
+		if !skip[item.TargetID] {
+			result = append(result, item)
+		}
+	}
+	for _, pin := range pins {
+		index := pin.Position - 1
+		if index < 0 {
+			index = 0
+		}
+		if index > len(result) {
+			index = len(result)
+		}
+		result = append(result, trendingItem{})
+		copy(result[index+1:], result[index:])
+		result[index] = trendingItem{TargetID: pin.TargetID, IsPinned: true}
+	}
+	if len(result) > size {
+		result = result[:size]
+	}
+	return result
+}
+
+// RefreshTrendingFromDB 重新计算视频和话题热榜并保存为新的快照，记录每个条目上一次的排名和最高排名，
+// 同时删除超过保留期的快照
+func RefreshTrendingFromDB() error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	trendingRefreshMu.Lock()
+	defer trendingRefreshMu.Unlock()
+
+	cfg := config.AppConfig.Trending
+	windowHours := float64(cfg.WindowHours)
+	if windowHours <= 0 {
+		windowHours = defaultTrendingWindowHours
+	}
+	retentionDays := cfg.RetentionDays
+	if retentionDays <= 0 {
+		retentionDays = defaultTrendingRetentionDays
+	}
+	size := trendingSize()
+	now := time.Now()
+	since := now.Add(-time.Duration(windowHours * float64(time.Hour)))
+
+	// 计算榜单并应用人工干预
+	overrides, err := getTrendingOverrides()
+	if err != nil {
+		return err
+	}
+	videos, err := computeTrendingVideos(since, windowHours, size)
+	if err != nil {
+		return err
+	}
+	hashtags, err := computeTrendingHashtags(since, windowHours, size)
+	if err != nil {
+		return err
+	}
+	lists := map[string][]trendingItem{
+		TrendingKindVideo:   applyTrendingOverrides(videos, overrides, TrendingKindVideo, size),
+		TrendingKindHashtag: applyTrendingOverrides(hashtags, overrides, TrendingKindHashtag, size),
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	var prevSnapshotID sql.NullInt64
+	err = tx.QueryRow(`SELECT id FROM trending_snapshots ORDER BY id DESC LIMIT 1`).Scan(&prevSnapshotID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("查询上一次热榜失败: %v", err)
+	}
+
+	var snapshotID int64
+	err = tx.QueryRow(`INSERT INTO trending_snapshots (computed_at) VALUES ($1) RETURNING id`, now).Scan(&snapshotID)
+	if err != nil {
+		return fmt.Errorf("保存热榜快照失败: %v", err)
+	}
+
+	retainSince := now.AddDate(0, 0, -retentionDays)
+	for _, kind := range []string{TrendingKindVideo, TrendingKindHashtag} {
+		if err := insertTrendingEntries(tx, snapshotID, prevSnapshotID, retainSince, kind, lists[kind]); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM trending_snapshots WHERE computed_at < $1 AND id <> $2
+	`, retainSince, snapshotID)
+	if err != nil {
+		return fmt.Errorf("清理热榜快照失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// insertTrendingEntries 保存一个榜单的条目，上一次排名取自上一份快照，
+// 最高排名为本次排名和保留期内（retainSince 之后）各快照排名中的最小值
+func insertTrendingEntries(tx *sql.Tx, snapshotID int64, prevSnapshotID sql.NullInt64, retainSince time.Time, kind string, items []trendingItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	targetIDs := make([]int64, len(items))
+	for i, item := range items {
+		targetIDs[i] = item.TargetID
+	}
+
+	rows, err := tx.Query(`
+		SELECT e.target_id,
+		       MAX(CASE WHEN e.snapshot_id = $3 THEN e.rank END),
+		       MIN(e.rank) FILTER (WHERE s.computed_at >= $4)
+		FROM trending_entries e
+		JOIN trending_snapshots s ON s.id = e.snapshot_id
+		WHERE e.kind = $1 AND e.target_id = ANY($2)
+		GROUP BY e.target_id
+	`, kind, pq.Array(targetIDs), prevSnapshotID, retainSince)
+	if err != nil {
+		return fmt.Errorf("查询历史排名失败: %v", err)
+	}
+	prevRanks := make(map[int64]sql.NullInt64)
+	peakRanks := make(map[int64]int)
+	for rows.Next() {
+		var targetID int64
+		var prevRank, peakRank sql.NullInt64
+		if err := rows.Scan(&targetID, &prevRank, &peakRank); err != nil {
+			rows.Close()
+			return fmt.Errorf("解析历史排名失败: %v", err)
+		}
+		prevRanks[targetID] = prevRank
+		if peakRank.Valid {
+			peakRanks[targetID] = int(peakRank.Int64)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询历史排名时发生错误: %v", err)
+	}
+
+	for i, item := range items {
+		rank := i + 1
+		peak := rank
+		if prevPeak, ok := peakRanks[item.TargetID]; ok && prevPeak < peak {
+			peak = prevPeak
+		}
+		_, err := tx.Exec(`
+			INSERT INTO trending_entries (snapshot_id, kind, target_id, rank, score, prev_rank, peak_rank, is_pinned)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, snapshotID, kind, item.TargetID, rank, item.Score, prevRanks[item.TargetID], peak, item.IsPinned)
+		if err != nil {
+			return fmt.Errorf("保存热榜条目失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// trendingRank 根据本次和上一次排名构建排名信息
+func trendingRank(rank int, prevRank sql.NullInt64, peakRank int, score float64, isPinned bool) TrendingRank {
+	r := TrendingRank{Rank: rank, PeakRank: peakRank, Score: score, IsPinned: isPinned}
+	switch {
+	case !prevRank.Valid:
+		r.Trend = TrendingNew
+	case int(prevRank.Int64) > rank:
+		r.Trend = TrendingUp
+		r.Change = int(prevRank.Int64) - rank
+	case int(prevRank.Int64) < rank:
+		r.Trend = TrendingDown
+		r.Change = int(prevRank.Int64) - rank
+	default:
+		r.Trend = TrendingSame
+	}
+	return r
+}
+
+// GetTrendingFromDB 获取最近一次计算的视频和话题热榜，对观看者不可见的视频会被跳过
+func GetTrendingFromDB(viewerID string) (Trending, error) {
+	if config.DB == nil {
+		return Trending{}, fmt.Errorf("数据库未初始化")
+	}
+	trending := Trending{Videos: []TrendingVideo{}, Hashtags: []TrendingHashtag{}}
+
+	var snapshotID int64
+	var computedAt time.Time
+	err := config.DB.QueryRow(`
+		SELECT id, computed_at FROM trending_snapshots ORDER BY id DESC LIMIT 1
+	`).Scan(&snapshotID, &computedAt)
+	if err == sql.ErrNoRows {
+		return trending, nil
+	}
+	if err != nil {
+		return Trending{}, fmt.Errorf("查询热榜快照失败: %v", err)
+	}
+	trending.UpdatedAt = computedAt.Unix()
+
+	rows, err := config.DB.Query(`
+		SELECT e.kind, e.target_id, COALESCE(v.aweme_id, ''), e.rank, COALESCE(e.score, 0),
+		       e.prev_rank, e.peak_rank, COALESCE(e.is_pinned, false)
+		FROM trending_entries e
+		LEFT JOIN videos v ON e.kind = 'video' AND v.id = e.target_id
+		WHERE e.snapshot_id = $1
+		ORDER BY e.kind, e.rank
+	`, snapshotID)
+	if err != nil {
+		return Trending{}, fmt.Errorf("查询热榜条目失败: %v", err)
+	}
+	defer rows.Close()
+
+	var videoRanks, hashtagRanks []TrendingRank
+	var awemeIDs []string
+	var hashtagIDs []int64
+	for rows.Next() {
+		var kind, awemeID string
+		var targetID int64
+		var rank, peakRank int
+		var score float64
+		var prevRank sql.NullInt64
+		var isPinned bool
+		if err := rows.Scan(&kind, &targetID, &awemeID, &rank, &score, &prevRank, &peakRank, &isPinned); err != nil {
+			return Trending{}, fmt.Errorf("解析热榜条目失败: %v", err)
+		}
+		switch kind {
+		case TrendingKindVideo:
+			videoRanks = append(videoRanks, trendingRank(rank, prevRank, peakRank, score, isPinned))
+			awemeIDs = append(awemeIDs, awemeID)
+		case TrendingKindHashtag:
+			hashtagRanks = append(hashtagRanks, trendingRank(rank, prevRank, peakRank, score, isPinned))
+			hashtagIDs = append(hashtagIDs, targetID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Trending{}, fmt.Errorf("查询热榜条目时发生错误: %v", err)
+	}
+
+	// 视频按观看者可见性过滤
+	videos, err := getVideosInOrder(awemeIDs, viewerID)
+	if err != nil {
+		return Trending{}, err
+	}
+	byAwemeID := make(map[string]Video, len(videos))
+	for _, video := range videos {
+		byAwemeID[video.AwemeID] = video
+	}
+	for i, awemeID := range awemeIDs {
+		if video, ok := byAwemeID[awemeID]; ok {
+			trending.Videos = append(trending.Videos, TrendingVideo{TrendingRank: videoRanks[i], Video: video})
+		}
+	}
+
+	hashtags, err := getHashtagsByID(hashtagIDs, viewerID)
+	if err != nil {
+		return Trending{}, err
+	}
+	for i, hashtagID := range hashtagIDs {
+		if hashtag, ok := hashtags[hashtagID]; ok {
+			trending.Hashtags = append(trending.Hashtags, TrendingHashtag{TrendingRank: hashtagRanks[i], Hashtag: hashtag})
+		}
+	}
+	return trending, nil
+}
+
+// resolveTrendingTarget 将视频 aweme_id 或话题名转换为内部ID
+func resolveTrendingTarget(kind, target string) (int64, error) {
+	var id int64
+	var err error
+	switch kind {
+	case TrendingKindVideo:
+		err = config.DB.QueryRow(`SELECT id FROM videos WHERE aweme_id = $1`, target).Scan(&id)
+		if err == sql.ErrNoRows {
+			return 0, ErrVideoNotFound
+		}
+	case TrendingKindHashtag:
+		err = config.DB.QueryRow(`SELECT id FROM hashtags WHERE name = $1`, normalizeHashtag(target)).Scan(&id)
+		if err == sql.ErrNoRows {
+			return 0, ErrHashtagNotFound
+		}
+	default:
+		return 0, fmt.Errorf("%w: 不支持的榜单类型 %s", ErrInvalidTrending, kind)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询热榜条目失败: %v", err)
+	}
+	return id, nil
+}
+
+// SetTrendingOverrideFromDB 管理员干预热榜：pin 将条目固定在第 position 位，remove 将条目移出热榜。
+// target 为视频 aweme_id 或话题名，修改后立即重新计算热榜
+func SetTrendingOverrideFromDB(kind, target, action string, position int, operatorID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	switch action {
+	case TrendingActionPin:
+		if position < 1 || position > trendingSize() {
+			return fmt.Errorf("%w: 固定位置必须在 1 到 %d 之间", ErrInvalidTrending, trendingSize())
+		}
+	case TrendingActionRemove:
+		position = 0
+	default:
+		return fmt.Errorf("%w: 不支持的操作 %s", ErrInvalidTrending, action)
+	}
+
+	targetID, err := resolveTrendingTarget(kind, target)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.DB.Exec(`
+		INSERT INTO trending_overrides (kind, target_id, action, position, operator_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, target_id) DO UPDATE SET
+			action = EXCLUDED.action,
+			position = EXCLUDED.position,
+			operator_id = EXCLUDED.operator_id,
+			created_at = CURRENT_TIMESTAMP
+	`, kind, targetID, action, position, operatorID)
+	if err != nil {
+		return fmt.Errorf("保存热榜干预失败: %v", err)
+	}
+	return RefreshTrendingFromDB()
+}
+
+// ClearTrendingOverrideFromDB 取消对热榜条目的干预，恢复按互动速度排名，修改后立即重新计算热榜
+func ClearTrendingOverrideFromDB(kind, target string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	targetID, err := resolveTrendingTarget(kind, target)
+	if err != nil {
+		return err
+	}
+
+	_, err = config.DB.Exec(`DELETE FROM trending_overrides WHERE kind = $1 AND target_id = $2`, kind, targetID)
+	if err != nil {
+		return fmt.Errorf("取消热榜干预失败: %v", err)
+	}
+	return RefreshTrendingFromDB()
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestApplyTrendingOverrides(t *testing.T) {
+	items := []trendingItem{{TargetID: 1, Score: 9}, {TargetID: 2, Score: 8}, {TargetID: 3, Score: 7}}
+	pin := func(id int64) trendingItem { return trendingItem{TargetID: id, IsPinned: true} }
+
+	tests := []struct {
+		name      string
+		overrides []trendingOverride
+		size      int
+		want      []trendingItem
+	}{
+		{
+			name: "no overrides",
+			size: 3,
+			want: items,
+		},
+		{
+			name:      "remove",
+			overrides: []trendingOverride{{Kind: TrendingKindVideo, TargetID: 2, Action: TrendingActionRemove}},
+			size:      3,
+			want:      []trendingItem{items[0], items[2]},
+		},
+		{
+			name:      "pin moves existing entry",
+			overrides: []trendingOverride{{Kind: TrendingKindVideo, TargetID: 3, Action: TrendingActionPin, Position: 1}},
+			size:      3,
+			want:      []trendingItem{pin(3), items[0], items[1]},
+		},
+		{
+			name: "pins inserted by position and truncated",
+			overrides: []trendingOverride{
+				{Kind: TrendingKindVideo, TargetID: 5, Action: TrendingActionPin, Position: 3},
+				{Kind: TrendingKindVideo, TargetID: 4, Action: TrendingActionPin, Position: 1},
+			},
+			size: 4,
+			want: []trendingItem{pin(4), items[0], pin(5), items[1]},
+		},
+		{
+			name:      "position beyond list appended",
+			overrides: []trendingOverride{{Kind: TrendingKindVideo, TargetID: 9, Action: TrendingActionPin, Position: 10}},
+			size:      10,
+			want:      []trendingItem{items[0], items[1], items[2], pin(9)},
+		},
+		{
+			name:      "other kind ignored",
+			overrides: []trendingOverride{{Kind: TrendingKindHashtag, TargetID: 1, Action: TrendingActionRemove}},
+			size:      3,
+			want:      items,
+		},
+	}
+	for _, tt := range tests {
+		input := append([]trendingItem(nil), items...)
+		got := applyTrendingOverrides(input, tt.overrides, TrendingKindVideo, tt.size)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: applyTrendingOverrides = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTrendingRank(t *testing.T) {
+	tests := []struct {
+		rank       int
+		prevRank   sql.NullInt64
+		wantTrend  string
+		wantChange int
+	}{
+		{3, sql.NullInt64{}, TrendingNew, 0},
+		{2, sql.NullInt64{Int64: 5, Valid: true}, TrendingUp, 3},
+		{4, sql.NullInt64{Int64: 1, Valid: true}, TrendingDown, -3},
+		{2, sql.NullInt64{Int64: 2, Valid: true}, TrendingSame, 0},
+	}
+	for _, tt := range tests {
+		got := trendingRank(tt.rank, tt.prevRank, 1, 0, false)
+		if got.Trend != tt.wantTrend || got.Change != tt.wantChange {
+			t.Errorf("trendingRank(%d, %+v) = %s %d, want %s %d",
+				tt.rank, tt.prevRank, got.Trend, got.Change, tt.wantTrend, tt.wantChange)
+		}
+	}
+}
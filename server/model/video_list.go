@@ -0,0 +1,249 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"klik/server/config"
+)
+
+// videoListColumns 通用视频列表查询的字段，与 queryVideoList 的扫描顺序对应。
+// 查询需要使用 videoListJoins，并将视频表别名为 v
+const videoListColumns = `
+	v.aweme_id, COALESCE(v.video_desc, ''), COALESCE(v.create_time, 0), COALESCE(v.author_user_id, ''),
+	COALESCE(v.duration, 0), COALESCE(v.share_url, ''), COALESCE(v.prevent_download, false), COALESCE(v.is_top, false),
+	COALESCE(u.uid, ''), COALESCE(u.nickname, ''), COALESCE(u.gender, 0), COALESCE(u.signature, ''),
+	COALESCE(u.avatar_168x168_uri, ''), COALESCE(u.avatar_168x168_url, ''),
+	COALESCE(u.avatar_300x300_uri, ''), COALESCE(u.avatar_300x300_url, ''),
+	COALESCE(u.follower_count, 0), COALESCE(u.following_count, 0), COALESCE(u.aweme_count, 0), COALESCE(u.total_favorited, 0),
+	COALESCE(vs.comment_count, 0), COALESCE(vs.digg_count, 0), COALESCE(vs.collect_count, 0),
+	COALESCE(vs.share_count, 0), COALESCE(vs.play_count, 0),
+	COALESCE(vst.allow_share, true), COALESCE(vst.private_status, 0), COALESCE(vst.part_see, 0),
+	COALESCE(vpa.uri, ''), COALESCE(vpa.url, ''), COALESCE(vpa.width, 0), COALESCE(vpa.height, 0),
+	COALESCE(vpa.data_size, 0), COALESCE(vpa.file_hash, ''),
+	COALESCE(vc.uri, ''), COALESCE(vc.url, '')`
+
+// videoListJoins 通用视频列表查询的关联表
+const videoListJoins = `
+	LEFT JOIN users u ON v.author_user_id = u.uid
+	LEFT JOIN video_statistics vs ON v.id = vs.video_id
+	LEFT JOIN video_status vst ON v.id = vst.video_id
+	LEFT JOIN LATERAL (
+		SELECT uri, url, width, height, data_size, file_hash
+		FROM video_play_addresses WHERE video_id = v.id ORDER BY id LIMIT 1
+	) vpa ON true
+	LEFT JOIN LATERAL (
+		SELECT uri, url FROM video_covers WHERE video_id = v.id ORDER BY id LIMIT 1
+	) vc ON true`
+
+// queryVideoList 执行通用视频列表查询并构建视频对象
+func queryVideoList(query string, args ...interface{}) ([]Video, error) {
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询视频数据失败: %v", err)
+	}
+	defer rows.Close()
+
+	videos := []Video{}
+	for rows.Next() {
+		video, err := scanVideoRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, video)
+	}
+
+	// 检查是否有查询错误
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询视频数据时发生错误: %v", err)
+	}
+
+	if err := enrichVideos(videos); err != nil {
+		return nil, err
+	}
+	return videos, nil
+}
+
+// queryVideoPage 按游标查询一页视频，返回下一页游标，没有更多时为空字符串。
+// query 在 videoListColumns 之后依次选出排序值和视频内部ID，按 (排序值, ID) 倒序排列，
+// 以 ($1, $2) 为游标位置、$3 为条数，其余参数从 $4 开始
+func queryVideoPage(cursor pageCursor, limit int, query string, args ...interface{}) ([]Video, string, error) {
+	args = append([]interface{}{cursor.Key, cursor.ID, limit + 1}, args...)
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询视频数据失败: %v", err)
+	}
+	defer rows.Close()
+
+	videos := []Video{}
+	var keys []pageKey
+	for rows.Next() {
+		var key pageKey
+		video, err := scanVideoRow(rows, &key.Key, &key.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		videos = append(videos, video)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("查询视频数据时发生错误: %v", err)
+	}
+
+	videos, next := cutPage(videos, keys, limit, cursor)
+	if err := enrichVideos(videos); err != nil {
+		return nil, "", err
+	}
+	return videos, next, nil
+}
+
+// scanVideoRow 解析一行 videoListColumns 并构建视频对象，extra 接收之后的额外字段
+func scanVideoRow(rows *sql.Rows, extra ...interface{}) (Video, error) {
+	var (
+		awemeID, desc, authorUserID, shareURL                        string
+		createTime                                                   int64
+		duration                                                     int
+		preventDownload, isTop                                       bool
+		userUID, nickname, signature                                 string
+		gender                                                       int
+		avatar168URI, avatar168URL, avatar300URI, avatar300URL       string
+		followerCount, followingCount, awemeCount, totalFavorited    int
+		commentCount, diggCount, collectCount, shareCount, playCount int
+		allowShare                                                   bool
+		privateStatus, partSee                                       int
+		playURI, playURL, fileHash                                   string
+		width, height                                                int
+		dataSize                                                     int64
+		coverURI, coverURL                                           string
+	)
+
+	// 扫描行数据
+	dest := []interface{}{
+		&awemeID, &desc, &createTime, &authorUserID,
+		&duration, &shareURL, &preventDownload, &isTop,
+		&userUID, &nickname, &gender, &signature,
+		&avatar168URI, &avatar168URL, &avatar300URI, &avatar300URL,
+		&followerCount, &followingCount, &awemeCount, &totalFavorited,
+		&commentCount, &diggCount, &collectCount, &shareCount, &playCount,
+		&allowShare, &privateStatus, &partSee,
+		&playURI, &playURL, &width, &height, &dataSize, &fileHash,
+		&coverURI, &coverURL,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return Video{}, fmt.Errorf("解析视频数据失败: %v", err)
+	}
+
+	// 默认地址，如果数据库中没有数据
+	if shareURL == "" {
+		shareURL = fmt.Sprintf("https://example.com/share/%s", awemeID)
+	}
+	if playURL == "" {
+		playURL = fmt.Sprintf("https://example.com/video/%s.mp4", awemeID)
+	}
+
+	// 构建视频对象
+	video := Video{
+		AwemeID:         awemeID,
+		Desc:            desc,
+		CreateTime:      createTime,
+		ShareURL:        shareURL,
+		Duration:        duration,
+		AuthorUserID:    authorUserID,
+		PreventDownload: preventDownload,
+		VideoInfo: VideoInfo{
+			PlayAddr: PlayAddr{
+				URI:      playURI,
+				URLList:  []string{playURL},
+				Width:    width,
+				Height:   height,
+				DataSize: dataSize,
+				FileHash: fileHash,
+			},
+			Cover: Cover{
+				URI:     coverURI,
+				URLList: []string{coverURL},
+			},
+			Height:   height,
+			Width:    width,
+			Ratio:    "540p",
+			Duration: duration,
+		},
+		Statistics: Statistics{
+			CommentCount: commentCount,
+			DiggCount:    diggCount,
+			CollectCount: collectCount,
+			PlayCount:    playCount,
+			ShareCount:   shareCount,
+		},
+		Status: StatusInfo{
+			AllowShare:    allowShare,
+			PartSee:       partSee,
+			PrivateStatus: privateStatus,
+		},
+		TextExtra: []TextExtra{},
+		IsTop:     boolToInt(isTop),
+		ShareInfo: ShareInfo{
+			ShareURL:      shareURL,
+			ShareLinkDesc: desc,
+		},
+		AwemeControl: AwemeControl{
+			CanForward:     true,
+			CanShare:       allowShare,
+			CanComment:     true,
+			CanShowComment: true,
+		},
+		Author: Author{
+			UID:       userUID,
+			Nickname:  nickname,
+			Gender:    gender,
+			Signature: signature,
+			Avatar168x168: Avatar{
+				URI:     avatar168URI,
+				URLList: []string{avatar168URL},
+				Width:   168,
+				Height:  168,
+			},
+			Avatar300x300: Avatar{
+				URI:     avatar300URI,
+				URLList: []string{avatar300URL},
+				Width:   300,
+				Height:  300,
+			},
+			FollowerCount:  followerCount,
+			FollowingCount: followingCount,
+			AwemeCount:     awemeCount,
+			TotalFavorited: totalFavorited,
+			UniqueID:       userUID,
+			CoverURL:       []CoverURL{},
+			WhiteCoverURL:  []CoverURL{},
+		},
+	}
+
+	return video, nil
+}
+
+// videoEnrichers 视频列表构建完成后按顺序执行的补充处理，按 aweme_id 批量填充额外信息。
+// 音乐信息统一由 fillVideoMusic 填充，列表查询本身不需要关联音乐表
+var videoEnrichers = []func(videos []Video) error{
+	fillVideoMusic,
+	fillVideoHashtags,
+	fillVideoMentions,
+	sortVideoTextExtra,
+	fillVideoSeries,
+	fillVideoSubtitles,
+	fillVideoChapters,
+	fillVideoCovers,
+	fillVideoImages,
+}
+
+// enrichVideos 为视频列表补充话题等额外信息，所有视频列表返回前都需要调用
+func enrichVideos(videos []Video) error {
+	if config.DB == nil || len(videos) == 0 {
+		return nil
+	}
+	for _, enrich := range videoEnrichers {
+		if err := enrich(videos); err != nil {
+			return err
+		}
+	}
+	return nil
+}
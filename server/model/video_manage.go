@@ -0,0 +1,482 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrVideoNotFound 视频不存在
+	ErrVideoNotFound = errors.New("视频不存在")
+	// ErrVideoForbidden 无权操作该视频
+	ErrVideoForbidden = errors.New("无权操作该视频")
+	// ErrVideoNotInTrash 视频不在回收站中或已超过保留期
+	ErrVideoNotInTrash = errors.New("视频不在回收站中或已超过保留期")
+)
+
+// VideoUpdateParams 视频编辑参数，字段为空表示不修改
+type VideoUpdateParams struct {
+	Desc            *string   `json:"desc"`
+	PrivateStatus   *int      `json:"private_status"`
+	PartSee         *int      `json:"part_see"`
+	PartSeeUserIDs  *[]string `json:"part_see_user_ids"`
+	AllowShare      *bool     `json:"allow_share"`
+	PreventDownload *bool     `json:"prevent_download"`
+}
+
+// TrashVideo 回收站中的视频
+type TrashVideo struct {
+	AwemeID    string `json:"aweme_id"`
+	Desc       string `json:"desc"`
+	CreateTime int64  `json:"create_time"`
+	DeleteTime int64  `json:"delete_time"`
+	ExpireTime int64  `json:"expire_time"`
+	Duration   int    `json:"duration"`
+	Cover      Cover  `json:"cover"`
+}
+
+// trashRetention 回收站保留时长
+func trashRetention() time.Duration {
+	days := config.AppConfig.Video.TrashRetentionDays
+	if days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// getOwnedVideoID 获取视频内部ID并校验作者身份
+func getOwnedVideoID(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, awemeID, userID string) (int64, error) {
+	var videoID int64
+	var authorUserID sql.NullString
+	err := q.QueryRow(`SELECT id, author_user_id FROM videos WHERE aweme_id = $1`, awemeID).Scan(&videoID, &authorUserID)
+	if err == sql.ErrNoRows {
+		return 0, ErrVideoNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询视频失败: %v", err)
+	}
+	if authorUserID.String != userID {
+		return 0, ErrVideoForbidden
+	}
+	return videoID, nil
+}
+
+// checkVideoNotDeleted 校验视频未被删除，已删除视频按不存在处理
+func checkVideoNotDeleted(tx *sql.Tx, videoID int64) error {
+	var isDelete bool
+	err := tx.QueryRow(`SELECT COALESCE(is_delete, false) FROM video_status WHERE video_id = $1`, videoID).Scan(&isDelete)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("查询视频状态失败: %v", err)
+	}
+	if isDelete {
+		return ErrVideoNotFound
+	}
+	return nil
+}
+
+// UpdateVideoFromDB 作者编辑自己的视频
+func UpdateVideoFromDB(awemeID, userID string, params VideoUpdateParams) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if err := validateVideoPrivacy(params.PrivateStatus, params.PartSee); err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+
+	// 已删除的视频只能先恢复再编辑
+	if err := checkVideoNotDeleted(tx, videoID); err != nil {
+		return err
+	}
+
+	// 更新视频基本信息
+	_, err = tx.Exec(`
+		UPDATE videos SET
+			video_desc = COALESCE($2, video_desc),
+			prevent_download = COALESCE($3, prevent_download),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, videoID, params.Desc, params.PreventDownload)
+	if err != nil {
+		return fmt.Errorf("更新视频信息失败: %v", err)
+	}
+
+	// 描述变化后重新解析话题和章节，并更新内容特征
+	if params.Desc != nil {
+		if err := saveVideoHashtags(tx, videoID, *params.Desc); err != nil {
+			return err
+		}
+		if err := saveDescChapters(tx, videoID, *params.Desc); err != nil {
+			return err
+		}
+		if err := saveVideoContentFeatures(tx, videoID); err != nil {
+			return err
+		}
+	}
+
+	// 更新视频状态，不存在时补建状态行
+	if params.PrivateStatus != nil || params.PartSee != nil || params.AllowShare != nil {
+		_, err = tx.Exec(`
+			INSERT INTO video_status (video_id, private_status, part_see, allow_share)
+			VALUES ($1, COALESCE($2, 0), COALESCE($3, 0), COALESCE($4, true))
+			ON CONFLICT (video_id) DO UPDATE SET
+				private_status = COALESCE($2, video_status.private_status),
+				part_see = COALESCE($3, video_status.part_see),
+				allow_share = COALESCE($4, video_status.allow_share),
+				updated_at = CURRENT_TIMESTAMP
+		`, videoID, params.PrivateStatus, params.PartSee, params.AllowShare)
+		if err != nil {
+			return fmt.Errorf("更新视频状态失败: %v", err)
+		}
+	}
+
+	// 更新部分可见用户列表
+	if params.PartSeeUserIDs != nil {
+		if err := setVideoPartSeeUsers(tx, videoID, *params.PartSeeUserIDs); err != nil {
+			return err
+		}
+	}
+
+	// 隐私更新后再处理提及，只通知能看到视频的新提及用户
+	if params.Desc != nil {
+		if err := saveVideoMentions(tx, videoID, userID, awemeID, *params.Desc); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// DeleteVideoFromDB 作者删除自己的视频（软删除，移入回收站）
+func DeleteVideoFromDB(awemeID, userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+
+	// 仅当视频从未删除变为已删除时才计数，保证重复删除不会多减作品数
+	var scheduled bool
+	err = tx.QueryRow(`
+		INSERT INTO video_status (video_id, is_delete, deleted_at)
+		VALUES ($1, true, CURRENT_TIMESTAMP)
+		ON CONFLICT (video_id) DO UPDATE SET
+			is_delete = true,
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE NOT COALESCE(video_status.is_delete, false)
+		RETURNING COALESCE(is_scheduled, false)
+	`, videoID).Scan(&scheduled)
+	if err == sql.ErrNoRows {
+		return ErrVideoNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("删除视频失败: %v", err)
+	}
+
+	// 删除的视频自动取消置顶，恢复后需重新置顶
+	if err := unpinVideo(tx, videoID, userID); err != nil {
+		return err
+	}
+
+	// 定时发布中的视频尚未计入作品数
+	if !scheduled {
+		_, err = tx.Exec(`UPDATE users SET aweme_count = GREATEST(aweme_count - 1, 0) WHERE uid = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("更新作品数失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// GetTrashVideosFromDB 获取用户回收站中的视频，最近删除的在前，返回下一页游标
+func GetTrashVideosFromDB(userID, token string, limit int) ([]TrashVideo, string, error) {
+	if config.DB == nil {
+		return nil, "", fmt.Errorf("数据库未初始化")
+	}
+	cursor, err := decodeCursor(token, "trash:"+userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	deletedAt := timestampPageKey("vst.deleted_at")
+	query := `
+		SELECT v.aweme_id, COALESCE(v.video_desc, ''), COALESCE(v.create_time, 0), COALESCE(v.duration, 0),
+		       vst.deleted_at,
+		       COALESCE(vc.uri, ''), COALESCE(vc.url, ''), COALESCE(vc.width, 0), COALESCE(vc.height, 0),
+		       ` + deletedAt + `, v.id
+		FROM videos v
+		JOIN video_status vst ON v.id = vst.video_id
+		LEFT JOIN LATERAL (
+			SELECT uri, url, width, height FROM video_covers WHERE video_id = v.id ORDER BY id LIMIT 1
+		) vc ON true
+		WHERE v.author_user_id = $1 AND vst.is_delete AND vst.deleted_at > $2
+		  AND (` + deletedAt + `, v.id) < ($3, $4)
+		ORDER BY ` + deletedAt + ` DESC, v.id DESC
+		LIMIT $5
+	`
+	rows, err := config.DB.Query(query, userID, time.Now().Add(-trashRetention()), cursor.Key, cursor.ID, limit+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询回收站视频失败: %v", err)
+	}
+	defer rows.Close()
+
+	videos := []TrashVideo{}
+	var keys []pageKey
+	for rows.Next() {
+		var video TrashVideo
+		var deletedAt time.Time
+		var coverURL string
+		var key pageKey
+		err := rows.Scan(
+			&video.AwemeID, &video.Desc, &video.CreateTime, &video.Duration,
+			&deletedAt,
+			&video.Cover.URI, &coverURL, &video.Cover.Width, &video.Cover.Height,
+			&key.Key, &key.ID,
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("解析回收站视频失败: %v", err)
+		}
+		video.DeleteTime = deletedAt.Unix()
+		video.ExpireTime = deletedAt.Add(trashRetention()).Unix()
+		video.Cover.URLList = []string{coverURL}
+		videos = append(videos, video)
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("查询回收站视频时发生错误: %v", err)
+	}
+
+	videos, next := cutPage(videos, keys, limit, cursor)
+	return videos, next, nil
+}
+
+// GetTrashVideoCountFromDB 获取用户回收站视频总数
+func GetTrashVideoCountFromDB(userID string) (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	query := `
+		SELECT COUNT(*) FROM videos v
+		JOIN video_status vst ON v.id = vst.video_id
+		WHERE v.author_user_id = $1 AND vst.is_delete AND vst.deleted_at > $2
+	`
+	var count int
+	err := config.DB.QueryRow(query, userID, time.Now().Add(-trashRetention())).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("查询回收站视频总数失败: %v", err)
+	}
+
+	return count, nil
+}
+
+// RestoreVideoFromDB 从回收站恢复视频
+func RestoreVideoFromDB(awemeID, userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+
+	var scheduled bool
+	err = tx.QueryRow(`
+		UPDATE video_status SET is_delete = false, deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE video_id = $1 AND is_delete AND deleted_at > $2
+		RETURNING COALESCE(is_scheduled, false)
+	`, videoID, time.Now().Add(-trashRetention())).Scan(&scheduled)
+	if err == sql.ErrNoRows {
+		return ErrVideoNotInTrash
+	}
+	if err != nil {
+		return fmt.Errorf("恢复视频失败: %v", err)
+	}
+
+	// 定时发布中的视频由发布任务到期时计入作品数
+	if !scheduled {
+		_, err = tx.Exec(`UPDATE users SET aweme_count = aweme_count + 1 WHERE uid = $1`, userID)
+		if err != nil {
+			return fmt.Errorf("更新作品数失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// PurgeExpiredVideosFromDB 彻底删除超过回收站保留期的视频及其本地文件，返回清理数量
+func PurgeExpiredVideosFromDB() (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT video_id FROM video_status
+		WHERE is_delete AND deleted_at <= $1
+	`, time.Now().Add(-trashRetention()))
+	if err != nil {
+		return 0, fmt.Errorf("查询过期视频失败: %v", err)
+	}
+	var videoIDs []int64
+	for rows.Next() {
+		var videoID int64
+		if err := rows.Scan(&videoID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("解析过期视频失败: %v", err)
+		}
+		videoIDs = append(videoIDs, videoID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("遍历过期视频时发生错误: %v", err)
+	}
+	rows.Close()
+
+	purged := 0
+	for _, videoID := range videoIDs {
+		if err := purgeVideo(videoID); err != nil {
+			log.Printf("清理视频 %d 失败: %v", videoID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// videoPurger 可执行删除视频的数据库连接或事务
+type videoPurger interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// purgeVideo 删除单个视频的数据行和本地媒体文件
+func purgeVideo(videoID int64) error {
+	files, err := deleteVideoRows(config.DB, videoID)
+	if err != nil {
+		return err
+	}
+
+	// 数据行删除成功后再删文件，避免文件已删但数据仍可见；
+	// 文件可能仍被原声音乐或草稿引用，只删除不再被引用的文件
+	removeUnreferencedMedia(files)
+	return nil
+}
+
+// deleteVideoRows 删除视频数据行，返回视频关联的媒体文件地址。
+// q 为事务时，调用方需在提交成功后再调用 removeUnreferencedMedia 删除文件
+func deleteVideoRows(q videoPurger, videoID int64) ([]string, error) {
+	files, err := getVideoMediaURLs(q, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 关联表均为 ON DELETE CASCADE，删除视频行即可
+	if _, err := q.Exec(`DELETE FROM videos WHERE id = $1`, videoID); err != nil {
+		return nil, fmt.Errorf("删除视频数据失败: %v", err)
+	}
+	return files, nil
+}
+
+// getVideoMediaURLs 获取视频关联的媒体文件地址
+func getVideoMediaURLs(q videoPurger, videoID int64) ([]string, error) {
+	rows, err := q.Query(`
+		SELECT url FROM video_play_addresses WHERE video_id = $1 AND url IS NOT NULL
+		UNION ALL
+		SELECT url FROM video_covers WHERE video_id = $1 AND url IS NOT NULL
+		UNION ALL
+		SELECT url FROM video_subtitles WHERE video_id = $1 AND url IS NOT NULL
+		UNION ALL
+		SELECT url FROM video_images WHERE video_id = $1 AND url IS NOT NULL
+	`, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("查询视频文件失败: %v", err)
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, fmt.Errorf("解析视频文件失败: %v", err)
+		}
+		urls = append(urls, url)
+	}
+	return urls, rows.Err()
+}
+
+// localMediaPath 将文件服务地址转换为本地路径，非本地文件返回空字符串
+func localMediaPath(url string) string {
+	prefix := strings.TrimSuffix(config.FileURL, "/") + "/"
+	if config.FileURL == "" || !strings.HasPrefix(url, prefix) {
+		return ""
+	}
+	rel := filepath.Clean("/" + strings.TrimPrefix(url, prefix))
+	return filepath.Join(config.DataPath, rel)
+}
+
+// mediaURI 获取本地文件服务地址对应的相对路径，外链原样返回
+func mediaURI(url string) string {
+	prefix := strings.TrimSuffix(config.FileURL, "/") + "/"
+	if config.FileURL == "" || !strings.HasPrefix(url, prefix) {
+		return url
+	}
+	return strings.TrimPrefix(url, prefix)
+}
+
+// removeLocalMediaFile 删除本地媒体文件，外链文件忽略
+func removeLocalMediaFile(url string) {
+	path := localMediaPath(url)
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除文件 %s 失败: %v", path, err)
+	}
+}
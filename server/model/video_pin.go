@@ -0,0 +1,234 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+)
+
+// MaxPinnedVideos 每个用户最多置顶的视频数
+const MaxPinnedVideos = 3
+
+// pinnedSortBase 置顶视频排序值的基数，远大于任何发布时间，保证置顶视频排在最前
+const pinnedSortBase int64 = 1 << 62
+
+// userVideoSortColumn 用户主页视频的排序值，计算方式与 userVideoSortKey 一致，视频表别名为 v
+var userVideoSortColumn = fmt.Sprintf(
+	`CASE WHEN COALESCE(v.is_top, false) THEN %d - COALESCE(v.top_order, 0) ELSE COALESCE(v.create_time, 0) END`,
+	pinnedSortBase,
+)
+
+// userVideoSortKey 用户主页视频的排序值，倒序排列时置顶视频在前并按置顶顺序排列，其余视频按发布时间倒序
+func userVideoSortKey(isTop bool, topOrder int, createTime int64) int64 {
+	if isTop {
+		return pinnedSortBase - int64(topOrder)
+	}
+	return createTime
+}
+
+var (
+	// ErrTooManyPinned 置顶视频数量已达上限
+	ErrTooManyPinned = fmt.Errorf("最多只能置顶 %d 个视频", MaxPinnedVideos)
+	// ErrInvalidPinOrder 置顶排序与当前置顶视频不一致
+	ErrInvalidPinOrder = errors.New("置顶排序必须包含且仅包含当前全部置顶视频")
+)
+
+// lockUserPins 锁定用户行，串行化同一用户的置顶操作
+func lockUserPins(tx *sql.Tx, userID string) error {
+	var id int
+	err := tx.QueryRow(`SELECT id FROM users WHERE uid = $1 FOR UPDATE`, userID).Scan(&id)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("锁定用户失败: %v", err)
+	}
+	return nil
+}
+
+// PinVideoFromDB 置顶视频，新置顶的视频排在已有置顶视频之后
+func PinVideoFromDB(awemeID, userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+	if err := checkVideoNotDeleted(tx, videoID); err != nil {
+		return err
+	}
+	if err := lockUserPins(tx, userID); err != nil {
+		return err
+	}
+
+	var isTop bool
+	if err := tx.QueryRow(`SELECT COALESCE(is_top, false) FROM videos WHERE id = $1`, videoID).Scan(&isTop); err != nil {
+		return fmt.Errorf("查询置顶状态失败: %v", err)
+	}
+	if isTop {
+		return nil
+	}
+
+	var pinned, maxOrder int
+	err = tx.QueryRow(`
+		SELECT COUNT(*), COALESCE(MAX(top_order), 0) FROM videos
+		WHERE author_user_id = $1 AND is_top
+	`, userID).Scan(&pinned, &maxOrder)
+	if err != nil {
+		return fmt.Errorf("查询置顶视频失败: %v", err)
+	}
+	if pinned >= MaxPinnedVideos {
+		return ErrTooManyPinned
+	}
+
+	_, err = tx.Exec(`
+		UPDATE videos SET is_top = true, top_order = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, videoID, maxOrder+1)
+	if err != nil {
+		return fmt.Errorf("置顶视频失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// UnpinVideoFromDB 取消置顶视频
+func UnpinVideoFromDB(awemeID, userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+	if err := lockUserPins(tx, userID); err != nil {
+		return err
+	}
+	if err := unpinVideo(tx, videoID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// unpinVideo 取消置顶并重新整理剩余置顶视频的顺序
+func unpinVideo(tx *sql.Tx, videoID int64, userID string) error {
+	_, err := tx.Exec(`
+		UPDATE videos SET is_top = false, top_order = 0, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND is_top
+	`, videoID)
+	if err != nil {
+		return fmt.Errorf("取消置顶失败: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE videos v SET top_order = r.rn
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY top_order, id) AS rn
+			FROM videos WHERE author_user_id = $1 AND is_top
+		) r
+		WHERE v.id = r.id
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("整理置顶顺序失败: %v", err)
+	}
+	return nil
+}
+
+// ReorderPinnedVideosFromDB 按给定顺序重排置顶视频，awemeIDs 必须是当前全部置顶视频
+func ReorderPinnedVideosFromDB(userID string, awemeIDs []string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockUserPins(tx, userID); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT aweme_id FROM videos WHERE author_user_id = $1 AND is_top`, userID)
+	if err != nil {
+		return fmt.Errorf("查询置顶视频失败: %v", err)
+	}
+	pinned := map[string]bool{}
+	for rows.Next() {
+		var awemeID string
+		if err := rows.Scan(&awemeID); err != nil {
+			rows.Close()
+			return fmt.Errorf("解析置顶视频失败: %v", err)
+		}
+		pinned[awemeID] = true
+	}
+	rows.Close()
+
+	if len(awemeIDs) != len(pinned) {
+		return ErrInvalidPinOrder
+	}
+	for i, awemeID := range awemeIDs {
+		if !pinned[awemeID] {
+			return ErrInvalidPinOrder
+		}
+		delete(pinned, awemeID)
+
+		_, err := tx.Exec(`UPDATE videos SET top_order = $2 WHERE aweme_id = $1`, awemeID, i+1)
+		if err != nil {
+			return fmt.Errorf("更新置顶顺序失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// GetPinnedVideoIDsFromDB 获取用户按顺序排列的置顶视频ID
+func GetPinnedVideoIDsFromDB(userID string) ([]string, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT v.aweme_id FROM videos v
+		WHERE v.author_user_id = $1 AND v.is_top AND `+videoVisibleCondition("$1")+`
+		ORDER BY v.top_order
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询置顶视频失败: %v", err)
+	}
+	defer rows.Close()
+
+	awemeIDs := []string{}
+	for rows.Next() {
+		var awemeID string
+		if err := rows.Scan(&awemeID); err != nil {
+			return nil, fmt.Errorf("解析置顶视频失败: %v", err)
+		}
+		awemeIDs = append(awemeIDs, awemeID)
+	}
+	return awemeIDs, rows.Err()
+}
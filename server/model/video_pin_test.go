@@ -0,0 +1,35 @@
+package model
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestUserVideoSortKey(t *testing.T) {
+	videos := []struct {
+		awemeID    string
+		isTop      bool
+		topOrder   int
+		createTime int64
+	}{
+		{"old", false, 0, 1600000000},
+		{"pin2", true, 2, 1500000000},
+		{"new", false, 0, 1700000000},
+		{"pin1", true, 1, 1400000000},
+		{"pin3", true, 3, 1800000000},
+	}
+
+	sort.Slice(videos, func(i, j int) bool {
+		return userVideoSortKey(videos[i].isTop, videos[i].topOrder, videos[i].createTime) >
+			userVideoSortKey(videos[j].isTop, videos[j].topOrder, videos[j].createTime)
+	})
+	var got []string
+	for _, v := range videos {
+		got = append(got, v.awemeID)
+	}
+	want := []string{"pin1", "pin2", "pin3", "new", "old"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("user video order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,457 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidVideoType 不支持的视频类型
+	ErrInvalidVideoType = errors.New("不支持的视频类型")
+	// ErrInvalidPublishTime 定时发布时间无效
+	ErrInvalidPublishTime = errors.New("定时发布时间无效")
+	// ErrVideoNotScheduled 视频不是定时发布状态
+	ErrVideoNotScheduled = errors.New("视频不是定时发布状态")
+)
+
+// 允许上传的视频类型
+var uploadVideoTypes = map[string]bool{
+	"recommend-video": true,
+	"long-video":      true,
+}
+
+// VideoCreateParams 发布视频参数
+type VideoCreateParams struct {
+	AuthorUserID  string
+	Desc          string
+	VideoType     string
+	Duration      int
+	PrivateStatus int
+	PublishTime   int64 // 定时发布时间（秒级时间戳），0 表示立即发布
+	PlayURI       string
+	PlayURL       string
+	Width         int
+	Height        int
+	DataSize      int64
+	FileHash      string
+	MusicID       int64            // 配乐ID，0 表示无配乐
+	OriginalSound bool             // 将视频声音登记为原声并作为配乐，不能与 MusicID 同时使用
+	CoverURL      string           // 封面地址，为空表示无封面
+	Images        []PhotoImageFile // 图文作品的图片，仅 VideoType 为 photo 时使用
+}
+
+// ScheduledVideo 定时发布中的视频
+type ScheduledVideo struct {
+	AwemeID     string `json:"aweme_id"`
+	Desc        string `json:"desc"`
+	VideoType   string `json:"type"`
+	Duration    int    `json:"duration"`
+	PublishTime int64  `json:"publish_time"`
+	PlayAddr    Cover  `json:"play_addr"`
+}
+
+// newAwemeID 生成视频ID
+func newAwemeID() string {
+	return strconv.FormatInt(time.Now().UnixMilli()*1000+rand.Int63n(1000), 10)
+}
+
+// maxScheduleDuration 定时发布最多可提前的时长
+func maxScheduleDuration() time.Duration {
+	days := config.AppConfig.Video.MaxScheduleDays
+	if days <= 0 {
+		days = 14
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// validatePublishTime 校验定时发布时间，必须在当前时间之后且不超过最大提前天数
+func validatePublishTime(publishTime int64) error {
+	now := time.Now()
+	if publishTime <= now.Unix() || publishTime > now.Add(maxScheduleDuration()).Unix() {
+		return ErrInvalidPublishTime
+	}
+	return nil
+}
+
+// CreateVideoFromDB 发布视频，返回视频ID。指定了发布时间的视频在到期前保持隐藏
+func CreateVideoFromDB(params VideoCreateParams) (string, error) {
+	if config.DB == nil {
+		return "", fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return "", fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	awemeID, err := insertVideo(tx, params)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("提交事务失败: %v", err)
+	}
+	return awemeID, nil
+}
+
+// insertVideo 在事务中校验参数并写入视频及其关联表
+func insertVideo(tx *sql.Tx, params VideoCreateParams) (string, error) {
+	if params.VideoType == "" {
+		params.VideoType = "recommend-video"
+	}
+	isPhoto := params.VideoType == VideoTypePhoto
+	if isPhoto {
+		if err := validatePhotoPost(params); err != nil {
+			return "", err
+		}
+		if params.CoverURL == "" {
+			params.CoverURL = params.Images[0].URL
+		}
+	} else if !uploadVideoTypes[params.VideoType] {
+		return "", ErrInvalidVideoType
+	} else if len(params.Images) > 0 {
+		return "", fmt.Errorf("%w: 视频作品不能包含图片", ErrInvalidPhotoPost)
+	}
+	privateStatus := params.PrivateStatus
+	if err := validateVideoPrivacy(&privateStatus, nil); err != nil {
+		return "", err
+	}
+	scheduled := params.PublishTime != 0
+	createTime := time.Now().Unix()
+	if scheduled {
+		if err := validatePublishTime(params.PublishTime); err != nil {
+			return "", err
+		}
+		createTime = params.PublishTime
+	}
+
+	musicID := params.MusicID
+	if params.OriginalSound {
+		if musicID != 0 {
+			return "", ErrMusicConflict
+		}
+		var err error
+		musicID, err = createOriginalMusic(tx, MusicCreateParams{
+			OwnerUserID: params.AuthorUserID,
+			PlayURL:     params.PlayURL,
+			Duration:    params.Duration / 1000, // 视频时长为毫秒，音乐时长为秒
+		})
+		if err != nil {
+			return "", err
+		}
+	} else if musicID != 0 {
+		if err := checkMusicExists(tx, musicID); err != nil {
+			return "", err
+		}
+	}
+
+	awemeID := newAwemeID()
+	var videoID int64
+	err := tx.QueryRow(`
+		INSERT INTO videos (aweme_id, video_desc, create_time, music_id, author_user_id, duration, video_type, share_url)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8)
+		RETURNING id
+	`, awemeID, params.Desc, createTime, musicID, params.AuthorUserID, params.Duration, params.VideoType,
+		fmt.Sprintf("https://example.com/share/%s", awemeID)).Scan(&videoID)
+	if err != nil {
+		return "", fmt.Errorf("保存视频失败: %v", err)
+	}
+
+	if err := saveVideoHashtags(tx, videoID, params.Desc); err != nil {
+		return "", err
+	}
+	if err := saveDescChapters(tx, videoID, params.Desc); err != nil {
+		return "", err
+	}
+	if err := saveVideoContentFeatures(tx, videoID); err != nil {
+		return "", err
+	}
+
+	if isPhoto {
+		if err := insertPhotoImages(tx, videoID, params.Images); err != nil {
+			return "", err
+		}
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO video_play_addresses (video_id, uri, url, width, height, data_size, file_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, videoID, params.PlayURI, params.PlayURL, params.Width, params.Height, params.DataSize, params.FileHash)
+		if err != nil {
+			return "", fmt.Errorf("保存视频播放地址失败: %v", err)
+		}
+	}
+
+	if params.CoverURL != "" {
+		_, err = tx.Exec(`
+			INSERT INTO video_covers (video_id, uri, url) VALUES ($1, $2, $3)
+		`, videoID, mediaURI(params.CoverURL), params.CoverURL)
+		if err != nil {
+			return "", fmt.Errorf("保存视频封面失败: %v", err)
+		}
+	}
+
+	if _, err := tx.Exec(`INSERT INTO video_statistics (video_id) VALUES ($1)`, videoID); err != nil {
+		return "", fmt.Errorf("保存视频统计失败: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO video_status (video_id, private_status, is_scheduled, publish_time)
+		VALUES ($1, $2, $3, $4)
+	`, videoID, privateStatus, scheduled, createTime)
+	if err != nil {
+		return "", fmt.Errorf("保存视频状态失败: %v", err)
+	}
+
+	if err := saveVideoMentions(tx, videoID, params.AuthorUserID, awemeID, params.Desc); err != nil {
+		return "", err
+	}
+
+	if !scheduled {
+		notify := privateStatus == VideoPrivacyPublic
+		if err := onVideoPublished(tx, params.AuthorUserID, awemeID, params.Desc, notify); err != nil {
+			return "", err
+		}
+	}
+
+	return awemeID, nil
+}
+
+// onVideoPublished 视频对外发布后更新作品数，notify 为 true 时通知关注者
+func onVideoPublished(tx *sql.Tx, authorID, awemeID, desc string, notify bool) error {
+	_, err := tx.Exec(`UPDATE users SET aweme_count = aweme_count + 1 WHERE uid = $1`, authorID)
+	if err != nil {
+		return fmt.Errorf("更新作品数失败: %v", err)
+	}
+
+	if !notify {
+		return nil
+	}
+	return notifyFollowers(tx, authorID, NoticeTypeNewVideo, awemeID, "发布了新视频："+desc)
+}
+
+// GetScheduledVideosFromDB 获取用户定时发布中的视频
+func GetScheduledVideosFromDB(userID string) ([]ScheduledVideo, error) {
+	if config.DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+
+	query := `
+		SELECT v.aweme_id, COALESCE(v.video_desc, ''), COALESCE(v.video_type, ''), COALESCE(v.duration, 0),
+		       vst.publish_time, COALESCE(vpa.uri, ''), COALESCE(vpa.url, '')
+		FROM videos v
+		JOIN video_status vst ON v.id = vst.video_id
+		LEFT JOIN LATERAL (
+			SELECT uri, url FROM video_play_addresses WHERE video_id = v.id ORDER BY id LIMIT 1
+		) vpa ON true
+		WHERE v.author_user_id = $1 AND vst.is_scheduled AND NOT COALESCE(vst.is_delete, false)
+		ORDER BY vst.publish_time
+	`
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询定时发布视频失败: %v", err)
+	}
+	defer rows.Close()
+
+	videos := []ScheduledVideo{}
+	for rows.Next() {
+		var video ScheduledVideo
+		var playURL string
+		err := rows.Scan(
+			&video.AwemeID, &video.Desc, &video.VideoType, &video.Duration,
+			&video.PublishTime, &video.PlayAddr.URI, &playURL,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("解析定时发布视频失败: %v", err)
+		}
+		video.PlayAddr.URLList = []string{playURL}
+		videos = append(videos, video)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询定时发布视频时发生错误: %v", err)
+	}
+
+	return videos, nil
+}
+
+// getScheduledVideoID 获取作者本人定时发布中的视频内部ID
+func getScheduledVideoID(tx *sql.Tx, awemeID, userID string) (int64, error) {
+	videoID, err := getOwnedVideoID(tx, awemeID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var scheduled bool
+	err = tx.QueryRow(`
+		SELECT COALESCE(is_scheduled, false) AND NOT COALESCE(is_delete, false)
+		FROM video_status WHERE video_id = $1 FOR UPDATE
+	`, videoID).Scan(&scheduled)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("查询视频状态失败: %v", err)
+	}
+	if !scheduled {
+		return 0, ErrVideoNotScheduled
+	}
+	return videoID, nil
+}
+
+// RescheduleVideoFromDB 修改定时发布时间
+func RescheduleVideoFromDB(awemeID, userID string, publishTime int64) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	if err := validatePublishTime(publishTime); err != nil {
+		return err
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getScheduledVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE video_status SET publish_time = $2, updated_at = CURRENT_TIMESTAMP WHERE video_id = $1
+	`, videoID, publishTime)
+	if err != nil {
+		return fmt.Errorf("修改发布时间失败: %v", err)
+	}
+	_, err = tx.Exec(`UPDATE videos SET create_time = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, videoID, publishTime)
+	if err != nil {
+		return fmt.Errorf("修改发布时间失败: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	return nil
+}
+
+// CancelScheduledVideoFromDB 取消定时发布，视频从未公开过，直接彻底删除
+func CancelScheduledVideoFromDB(awemeID, userID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	videoID, err := getScheduledVideoID(tx, awemeID, userID)
+	if err != nil {
+		return err
+	}
+	// 在持有行锁的事务内删除，避免后台任务在此期间发布视频并通知粉丝
+	files, err := deleteVideoRows(tx, videoID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+
+	removeUnreferencedMedia(files)
+	return nil
+}
+
+// PublishDueVideosFromDB 发布已到时间的定时视频，返回发布数量
+func PublishDueVideosFromDB() (int, error) {
+	if config.DB == nil {
+		return 0, fmt.Errorf("数据库未初始化")
+	}
+
+	rows, err := config.DB.Query(`
+		SELECT video_id FROM video_status
+		WHERE is_scheduled AND NOT COALESCE(is_delete, false) AND publish_time <= $1
+		ORDER BY publish_time
+	`, time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("查询到期视频失败: %v", err)
+	}
+	var videoIDs []int64
+	for rows.Next() {
+		var videoID int64
+		if err := rows.Scan(&videoID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("解析到期视频失败: %v", err)
+		}
+		videoIDs = append(videoIDs, videoID)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("遍历到期视频时发生错误: %v", err)
+	}
+	rows.Close()
+
+	published := 0
+	for _, videoID := range videoIDs {
+		ok, err := publishScheduledVideo(videoID)
+		if err != nil {
+			log.Printf("定时发布视频 %d 失败: %v", videoID, err)
+			continue
+		}
+		if ok {
+			published++
+		}
+	}
+	return published, nil
+}
+
+// publishScheduledVideo 发布单个定时视频，已被其他实例发布时返回 false
+func publishScheduledVideo(videoID int64) (bool, error) {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer tx.Rollback()
+
+	var privateStatus, partSee int
+	err = tx.QueryRow(`
+		UPDATE video_status SET is_scheduled = false, updated_at = CURRENT_TIMESTAMP
+		WHERE video_id = $1 AND is_scheduled AND NOT COALESCE(is_delete, false)
+		RETURNING COALESCE(private_status, 0), COALESCE(part_see, 0)
+	`, videoID).Scan(&privateStatus, &partSee)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("更新发布状态失败: %v", err)
+	}
+
+	var awemeID, authorID, desc string
+	err = tx.QueryRow(`
+		SELECT aweme_id, author_user_id, COALESCE(video_desc, '') FROM videos WHERE id = $1
+	`, videoID).Scan(&awemeID, &authorID, &desc)
+	if err != nil {
+		return false, fmt.Errorf("查询视频失败: %v", err)
+	}
+
+	// 仅公开视频通知关注者
+	notify := privateStatus == VideoPrivacyPublic && partSee == VideoPartSeeNone
+	if err := onVideoPublished(tx, authorID, awemeID, desc, notify); err != nil {
+		return false, err
+	}
+	if err := notifyVideoMentions(tx, videoID, authorID, awemeID, desc, nil); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return true, nil
+}
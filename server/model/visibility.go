@@ -0,0 +1,161 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"klik/server/config"
+	"strings"
+)
+
+// 视频隐私状态，对应 video_status.private_status
+const (
+	VideoPrivacyPublic  = 0 // 公开
+	VideoPrivacyPrivate = 1 // 仅自己可见
+	VideoPrivacyFriends = 2 // 好友可见
+)
+
+// 部分可见状态，对应 video_status.part_see
+const (
+	VideoPartSeeNone     = 0 // 不限制
+	VideoPartSeeSelected = 1 // 仅 video_part_see_users 中的用户可见
+)
+
+// ErrInvalidPrivacy 隐私设置参数错误
+var ErrInvalidPrivacy = errors.New("隐私设置参数错误")
+
+// videoHiddenReasons 视频对非作者隐藏的条件，{viewer} 为观看者 uid 占位参数
+const videoHiddenReasons = `
+	vst.is_prohibited
+	OR vst.in_reviewing
+	OR vst.private_status = 1
+	OR (vst.private_status = 2 AND NOT EXISTS (
+		SELECT 1 FROM user_friends uf
+		JOIN users ua ON ua.id = uf.user_id
+		JOIN users ub ON ub.id = uf.friend_id
+		WHERE (ua.uid = v.author_user_id AND ub.uid = {viewer})
+		   OR (ua.uid = {viewer} AND ub.uid = v.author_user_id)
+	))
+	OR (vst.part_see = 1 AND NOT EXISTS (
+		SELECT 1 FROM video_part_see_users vpu
+		WHERE vpu.video_id = v.id AND vpu.user_id = {viewer}
+	))`
+
+// postHiddenReasons 图文对非作者隐藏的条件，{viewer} 为观看者 uid 占位参数
+const postHiddenReasons = `
+	pst.is_prohibited
+	OR pst.in_reviewing
+	OR pst.private_status = 1
+	OR (pst.private_status = 2 AND NOT EXISTS (
+		SELECT 1 FROM user_friends uf
+		JOIN users ua ON ua.id = uf.user_id
+		JOIN users ub ON ub.id = uf.friend_id
+		WHERE (ua.uid = p.author_user_id AND ub.uid = {viewer})
+		   OR (ua.uid = {viewer} AND ub.uid = p.author_user_id)
+	))`
+
+// videoVisibleCondition 返回视频对观看者可见的查询条件，要求视频表别名为 v。
+// 已删除和未到发布时间的视频对所有人隐藏；私密、违规和审核中的视频只对作者本人可见；
+// 好友可见的视频对作者和作者的好友可见，部分可见的视频对作者和 video_part_see_users 中的用户可见。
+// 没有 video_status 记录的视频按公开处理。
+func videoVisibleCondition(viewerParam string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM video_status vst
+		WHERE vst.video_id = v.id AND (
+			vst.is_delete
+			OR vst.is_scheduled
+			OR (v.author_user_id IS DISTINCT FROM ` + viewerParam + ` AND (` +
+		strings.ReplaceAll(videoHiddenReasons, "{viewer}", viewerParam) + `
+			))
+		)
+	)`
+}
+
+// postVisibleCondition 返回图文对观看者可见的查询条件，要求图文表别名为 p。
+// 规则与 videoVisibleCondition 一致：已删除的图文对所有人隐藏；私密、违规和审核中的图文只对作者本人可见；
+// 好友可见的图文对作者和作者的好友可见。没有 post_status 记录的图文按公开处理。
+func postVisibleCondition(viewerParam string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM post_status pst
+		WHERE pst.post_id = p.id AND (
+			pst.is_delete
+			OR (p.author_user_id IS DISTINCT FROM ` + viewerParam + ` AND (` +
+		strings.ReplaceAll(postHiddenReasons, "{viewer}", viewerParam) + `
+			))
+		)
+	)`
+}
+
+// videoPublicCondition 返回视频对所有人公开可见的查询条件，要求视频表别名为 v
+func videoPublicCondition() string {
+	return videoVisibleCondition("NULL")
+}
+
+// authorNotBlockedCondition 返回作者与观看者之间没有拉黑关系的查询条件，要求视频表别名为 v
+func authorNotBlockedCondition(viewerParam string) string {
+	return userNotBlockedCondition("v.author_user_id", viewerParam)
+}
+
+// userNotBlockedCondition 返回用户与观看者之间没有拉黑关系的查询条件，userColumn 为用户 uid 字段
+func userNotBlockedCondition(userColumn, viewerParam string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM user_blocks b
+		WHERE (b.blocker_id = ` + userColumn + ` AND b.blocked_id = ` + viewerParam + `)
+		   OR (b.blocker_id = ` + viewerParam + ` AND b.blocked_id = ` + userColumn + `)
+	)`
+}
+
+// getVisibleVideoID 获取对观看者可见的视频内部ID，不存在或不可见时返回 ErrVideoNotFound
+func getVisibleVideoID(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}, awemeID, viewerID string) (int64, error) {
+	var videoID int64
+	err := q.QueryRow(`
+		SELECT v.id FROM videos v WHERE v.aweme_id = $1 AND `+videoVisibleCondition("$2"),
+		awemeID, viewerID).Scan(&videoID)
+	if err == sql.ErrNoRows {
+		return 0, ErrVideoNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("查询视频失败: %v", err)
+	}
+	return videoID, nil
+}
+
+// CheckVideoVisibleFromDB 校验视频对观看者可见
+func CheckVideoVisibleFromDB(awemeID, viewerID string) error {
+	if config.DB == nil {
+		return fmt.Errorf("数据库未初始化")
+	}
+	_, err := getVisibleVideoID(config.DB, awemeID, viewerID)
+	return err
+}
+
+// validateVideoPrivacy 校验隐私设置参数
+func validateVideoPrivacy(privateStatus, partSee *int) error {
+	if privateStatus != nil && (*privateStatus < VideoPrivacyPublic || *privateStatus > VideoPrivacyFriends) {
+		return ErrInvalidPrivacy
+	}
+	if partSee != nil && *partSee != VideoPartSeeNone && *partSee != VideoPartSeeSelected {
+		return ErrInvalidPrivacy
+	}
+	return nil
+}
+
+// setVideoPartSeeUsers 覆盖设置部分可见视频的可见用户列表
+func setVideoPartSeeUsers(tx *sql.Tx, videoID int64, userIDs []string) error {
+	if _, err := tx.Exec(`DELETE FROM video_part_see_users WHERE video_id = $1`, videoID); err != nil {
+		return fmt.Errorf("清空可见用户失败: %v", err)
+	}
+	for _, userID := range userIDs {
+		_, err := tx.Exec(`
+			INSERT INTO video_part_see_users (video_id, user_id)
+			SELECT $1, uid FROM users WHERE uid = $2
+			ON CONFLICT DO NOTHING
+		`, videoID, userID)
+		if err != nil {
+			return fmt.Errorf("设置可见用户失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package router
 
 import (
+	"klik/server/config"
 	"klik/server/controller"
+	"klik/server/media"
+	"log"
 	"net/http"
 
 	"github.com/gin-contrib/cors"
@@ -12,16 +15,25 @@ import (
 func InitRouter() *gin.Engine {
 	r := gin.Default()
 
+	// 只采信可信代理转发的客户端 IP，避免伪造 X-Forwarded-For 影响按 IP 推断的所在城市
+	if err := r.SetTrustedProxies(config.AppConfig.Server.TrustedProxies); err != nil {
+		log.Printf("可信代理配置无效，不信任任何代理: %v", err)
+		r.SetTrustedProxies(nil)
+	}
+
 	// 配置跨域
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
-	// 静态文件服务
+	// 静态文件服务，注册字幕、音视频的 Content-Type
+	if err := media.RegisterContentTypes(); err != nil {
+		log.Printf("注册媒体类型失败: %v", err)
+	}
 	r.StaticFS("/api/file", http.Dir("public/data"))
 
 	// API路由
@@ -32,11 +44,38 @@ func InitRouter() *gin.Engine {
 		{
 			video.GET("/recommended", controller.GetRecommendedVideos)
 			video.GET("/long/recommended", controller.GetLongRecommendedVideos)
+			video.GET("/following", controller.GetFollowingVideos)
+			video.GET("/friends", controller.GetFriendsVideos)
+			video.GET("/nearby", controller.GetNearbyVideos)
+			video.GET("/related", controller.GetRelatedVideos)
+			video.GET("/similar", controller.GetSimilarVideos)
 			video.GET("/comments", controller.GetVideoComments)
+			video.POST("/comments", controller.CreateVideoComment)
+			video.GET("/danmaku", controller.GetVideoDanmaku)
+			video.POST("/danmaku", controller.CreateVideoDanmaku)
+			video.GET("/danmaku/stream", controller.StreamVideoDanmaku)
 			video.GET("/private", controller.GetPrivateVideos)
 			video.GET("/like", controller.GetLikedVideos)
 			video.GET("/my", controller.GetMyVideos)
 			video.GET("/history", controller.GetHistoryVideos)
+			video.POST("/upload", controller.UploadVideo)
+			video.POST("/photo", controller.UploadPhotoPost)
+			video.GET("/scheduled", controller.GetScheduledVideos)
+			video.PATCH("/scheduled/:id", controller.RescheduleVideo)
+			video.DELETE("/scheduled/:id", controller.CancelScheduledVideo)
+			video.GET("/trash", controller.GetTrashVideos)
+			video.POST("/trash/:id/restore", controller.RestoreVideo)
+			video.PUT("/pin/order", controller.ReorderPinnedVideos)
+			video.POST("/:id/pin", controller.PinVideo)
+			video.DELETE("/:id/pin", controller.UnpinVideo)
+			video.GET("/:id/next", controller.GetNextEpisode)
+			video.POST("/:id/play", controller.RecordVideoPlay)
+			video.POST("/:id/cover", controller.UploadVideoCover)
+			video.POST("/:id/subtitles", controller.UploadVideoSubtitle)
+			video.PUT("/:id/chapters", controller.SetVideoChapters)
+			video.DELETE("/:id/subtitles/:lang", controller.DeleteVideoSubtitle)
+			video.PATCH("/:id", controller.UpdateVideo)
+			video.DELETE("/:id", controller.DeleteVideo)
 		}
 
 		// 用户相关接口
@@ -46,8 +85,58 @@ func InitRouter() *gin.Engine {
 			user.GET("/video_list", controller.GetUserVideoList)
 			user.GET("/panel", controller.GetUserPanel)
 			user.GET("/friends", controller.GetUserFriends)
+			user.GET("/notifications", controller.GetNotifications)
+			user.GET("/privacy", controller.GetPrivacySettings)
+			user.PUT("/privacy", controller.UpdatePrivacySettings)
+			user.POST("/block/:uid", controller.BlockUser)
+			user.DELETE("/block/:uid", controller.UnblockUser)
+			user.DELETE("/seen", controller.ResetSeenVideos)
+		}
+
+		// 音乐相关接口
+		music := api.Group("/music")
+		{
+			music.POST("/upload", controller.UploadMusic)
+			music.GET("/:id", controller.GetMusicDetail)
+			music.GET("/:id/videos", controller.GetMusicVideos)
+		}
+
+		// 合集相关接口
+		series := api.Group("/series")
+		{
+			series.POST("", controller.CreateSeries)
+			series.GET("/:id", controller.GetSeries)
+			series.PUT("/:id/episodes", controller.UpdateSeriesEpisodes)
 		}
 
+		// 话题相关接口
+		api.GET("/tag/:name", controller.GetTagVideos)
+
+		// 热榜相关接口
+		trending := api.Group("/trending")
+		{
+			trending.GET("", controller.GetTrending)
+			trending.PUT("/:kind/:id", controller.SetTrendingOverride)
+			trending.DELETE("/:kind/:id", controller.ClearTrendingOverride)
+		}
+
+		// A/B 实验相关接口
+		api.GET("/experiments", controller.GetExperiments)
+
+		// 草稿相关接口
+		draft := api.Group("/draft")
+		{
+			draft.GET("", controller.GetDrafts)
+			draft.POST("", controller.CreateDraft)
+			draft.GET("/:id", controller.GetDraft)
+			draft.PATCH("/:id", controller.UpdateDraft)
+			draft.DELETE("/:id", controller.DeleteDraft)
+			draft.POST("/:id/publish", controller.PublishDraft)
+		}
+
+		// 媒体上传接口
+		api.POST("/media/upload", controller.UploadMedia)
+
 		// 帖子相关接口
 		post := api.Group("/post")
 		{
@@ -201,15 +201,15 @@ $$;
 
 -- 创建一些长视频示例
 INSERT INTO videos (aweme_id, video_desc, create_time, author_user_id, duration, video_type, share_url)
-VALUES ('long_video_1', '这是一个长视频示例', extract(epoch from now())::bigint, '59054327754', 300, 'long-video', 'https://example.com/share/long_video_1'),
-       ('long_video_2', '另一个长视频示例', extract(epoch from now())::bigint, '62839305427', 500, 'long-video', 'https://example.com/share/long_video_2')
-ON CONFLICT DO NOTHING;
+VALUES ('long_video_1', '这是一个长视频示例', extract(epoch from now())::bigint, '59054327754', 300000, 'long-video', 'https://example.com/share/long_video_1'),
+       ('long_video_2', '另一个长视频示例', extract(epoch from now())::bigint, '62839305427', 500000, 'long-video', 'https://example.com/share/long_video_2')
+ON CONFLICT (aweme_id) DO UPDATE SET duration = EXCLUDED.duration;
 
 -- 创建一些其他类型的视频
 INSERT INTO videos (aweme_id, video_desc, create_time, author_user_id, duration, video_type, share_url)
-VALUES ('private_video_1', '这是一个私人视频', extract(epoch from now())::bigint, '59054327754', 120, 'private-video', 'https://example.com/share/private_video_1'),
-       ('liked_video_1', '这是一个喜欢的视频', extract(epoch from now())::bigint, '62839305427', 60, 'liked-video', 'https://example.com/share/liked_video_1')
-ON CONFLICT DO NOTHING;
+VALUES ('private_video_1', '这是一个私人视频', extract(epoch from now())::bigint, '59054327754', 120000, 'private-video', 'https://example.com/share/private_video_1'),
+       ('liked_video_1', '这是一个喜欢的视频', extract(epoch from now())::bigint, '62839305427', 60000, 'liked-video', 'https://example.com/share/liked_video_1')
+ON CONFLICT (aweme_id) DO UPDATE SET duration = EXCLUDED.duration;
 
 -- 为所有新增视频添加统计信息
 DO $$
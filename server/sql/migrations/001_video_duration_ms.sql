@@ -0,0 +1,36 @@
+-- 将 videos.duration 从秒换算为毫秒
+--
+-- 早期版本的 videos.duration 以秒保存，现在上传时解析音视频文件得到毫秒时长，长视频列表也按毫秒过滤
+-- （duration > 60000）。在已有数据库上部署新版本前执行一次本脚本，否则旧视频会从长视频列表中消失，
+-- 章节和弹幕的时长校验也会出错。
+--
+-- 脚本在 schema_migrations 中登记，重复执行不会再次换算；新建的数据库（schema.sql）已登记本迁移，无需执行。
+-- 按秒保存的时长不会超过 36000（10 小时），大于该值的记录已经是毫秒，不做换算。
+-- 新版本部署后上传的时长不足 36 秒的视频无法与秒区分，因此务必在新版本开始接收上传之前执行。
+--
+-- 执行方式：psql -d douyin -f server/sql/migrations/001_video_duration_ms.sql
+
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version    VARCHAR(100) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+BEGIN;
+
+-- 锁定登记表，避免两个会话同时执行
+LOCK TABLE schema_migrations IN EXCLUSIVE MODE;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM schema_migrations WHERE version = '001_video_duration_ms') THEN
+        RAISE NOTICE '001_video_duration_ms 已执行，跳过';
+        RETURN;
+    END IF;
+
+    UPDATE videos SET duration = duration * 1000 WHERE duration BETWEEN 1 AND 36000;
+
+    INSERT INTO schema_migrations (version) VALUES ('001_video_duration_ms');
+END $$;
+
+COMMIT;
@@ -106,6 +106,8 @@ CREATE TABLE music
     album          VARCHAR(255),
     cover_uri      VARCHAR(255),
     cover_url      TEXT,
+    cover_width    INTEGER                  DEFAULT 0,  -- 封面实际尺寸，未知时为 0
+    cover_height   INTEGER                  DEFAULT 0,
     play_url       TEXT,
     duration       INTEGER                  DEFAULT 0,
     owner_id       VARCHAR(50),
@@ -127,10 +129,11 @@ CREATE TABLE videos
     create_time      BIGINT,
     music_id         BIGINT REFERENCES music (id),
     author_user_id   VARCHAR(50) REFERENCES users (uid),
-    duration         INTEGER                  DEFAULT 0,
+    duration         INTEGER                  DEFAULT 0,   -- 视频时长（毫秒），音乐表 duration 为秒
     video_type       VARCHAR(50)              DEFAULT 'recommend-video',
     share_url        TEXT,
     is_top           BOOLEAN                  DEFAULT FALSE,
+    top_order        INTEGER                  DEFAULT 0,  -- 置顶顺序，从 1 开始
     prevent_download BOOLEAN                  DEFAULT FALSE,
     is_ads           BOOLEAN                  DEFAULT FALSE,
     is_hash_tag      BOOLEAN                  DEFAULT FALSE,
@@ -165,6 +168,8 @@ CREATE TABLE video_covers
     url        TEXT,
     width      INTEGER                  DEFAULT 0,
     height     INTEGER                  DEFAULT 0,
+    variant    VARCHAR(20),                       -- 自定义封面的尺寸：large、medium、small
+    is_custom  BOOLEAN                  DEFAULT FALSE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -177,6 +182,7 @@ CREATE TABLE video_statistics
     digg_count    INTEGER                  DEFAULT 0,
     collect_count INTEGER                  DEFAULT 0,
     play_count    INTEGER                  DEFAULT 0,
+    finish_count  INTEGER                  DEFAULT 0,  -- 完播次数，观看时长达到视频时长 90% 计一次
     share_count   INTEGER                  DEFAULT 0,
     created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
@@ -191,11 +197,137 @@ CREATE TABLE video_status
     allow_share    BOOLEAN                  DEFAULT TRUE,
     is_prohibited  BOOLEAN                  DEFAULT FALSE,
     in_reviewing   BOOLEAN                  DEFAULT FALSE,
-    private_status INTEGER                  DEFAULT 0,
+    private_status INTEGER                  DEFAULT 0,  -- 0 公开，1 仅自己可见，2 好友可见
+    part_see       INTEGER                  DEFAULT 0,  -- 1 表示仅 video_part_see_users 中的用户可见
+    is_scheduled   BOOLEAN                  DEFAULT FALSE, -- 定时发布中，到发布时间前对所有人隐藏
+    publish_time   BIGINT,                              -- 定时发布时间（秒级时间戳）
+    deleted_at     TIMESTAMP WITH TIME ZONE,            -- 删除时间，用于回收站保留期计算
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id)
+);
+
+-- 创建视频部分可见用户表
+CREATE TABLE video_part_see_users
+(
+    id         SERIAL PRIMARY KEY,
+    video_id   INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    user_id    VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id, user_id)
+);
+
+-- 创建话题表
+CREATE TABLE hashtags
+(
+    id         SERIAL PRIMARY KEY,
+    name       VARCHAR(100) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建视频话题表，start_index/end_index 为话题在描述中的字符偏移
+CREATE TABLE video_hashtags
+(
+    id          SERIAL PRIMARY KEY,
+    video_id    INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    hashtag_id  INTEGER REFERENCES hashtags (id) ON DELETE CASCADE,
+    start_index INTEGER                  DEFAULT 0,
+    end_index   INTEGER                  DEFAULT 0,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id, start_index)
+);
+
+-- 创建视频提及用户表，start_index/end_index 为 @用户 在描述中的字符偏移
+CREATE TABLE video_mentions
+(
+    id          SERIAL PRIMARY KEY,
+    video_id    INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    user_id     VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    start_index INTEGER                  DEFAULT 0,
+    end_index   INTEGER                  DEFAULT 0,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id, start_index)
+);
+
+-- 创建合集表
+CREATE TABLE series
+(
+    id             SERIAL PRIMARY KEY,
+    series_id      VARCHAR(50) UNIQUE NOT NULL,
+    author_user_id VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    title          VARCHAR(100)       NOT NULL,
+    cover_url      TEXT,                                -- 为空时使用第一集的封面
+    description    TEXT,
     created_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 创建合集剧集表，一个视频最多属于一个合集，episode 从 1 开始
+CREATE TABLE series_videos
+(
+    id         SERIAL PRIMARY KEY,
+    series_id  INTEGER REFERENCES series (id) ON DELETE CASCADE,
+    video_id   INTEGER UNIQUE REFERENCES videos (id) ON DELETE CASCADE,
+    episode    INTEGER            NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (series_id, episode)
+);
+
+-- 创建视频字幕表，每个视频每种语言一条字幕，文件统一保存为 WebVTT
+CREATE TABLE video_subtitles
+(
+    id         SERIAL PRIMARY KEY,
+    video_id   INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    language   VARCHAR(20)        NOT NULL,  -- 语言代码，如 zh-CN、en
+    label      VARCHAR(50),                  -- 播放器中显示的名称
+    uri        VARCHAR(255),
+    url        TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id, language)
+);
+
+-- 创建图文作品图片表，video_type 为 photo 的作品没有播放地址，按 image_index 顺序展示图片
+CREATE TABLE video_images
+(
+    id          SERIAL PRIMARY KEY,
+    video_id    INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    image_index INTEGER            NOT NULL,  -- 图片顺序，从 0 开始
+    uri         VARCHAR(255),
+    url         TEXT               NOT NULL,
+    width       INTEGER            NOT NULL,
+    height      INTEGER            NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id, image_index)
+);
+
+-- 创建视频章节表，start_ms 为章节开始的播放时间（毫秒）
+CREATE TABLE video_chapters
+(
+    id         SERIAL PRIMARY KEY,
+    video_id   INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    start_ms   INTEGER            NOT NULL,
+    title      VARCHAR(100)       NOT NULL,
+    source     SMALLINT                 DEFAULT 0,  -- 0 从描述解析，1 作者手动设置
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (video_id, start_ms)
+);
+
+-- 创建弹幕表，offset_ms 为弹幕在视频中出现的播放时间（毫秒）
+CREATE TABLE danmaku
+(
+    id         SERIAL PRIMARY KEY,
+    danmaku_id VARCHAR(50) UNIQUE NOT NULL,
+    video_id   INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    user_id    VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    content    VARCHAR(255)       NOT NULL,
+    offset_ms  INTEGER            NOT NULL,
+    color      INTEGER                  DEFAULT 16777215,  -- RGB 颜色，默认白色
+    position   SMALLINT                 DEFAULT 0,         -- 0 滚动，1 顶部，2 底部
+    font_size  SMALLINT                 DEFAULT 25,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 -- 创建帖子表
 CREATE TABLE posts
 (
@@ -216,6 +348,7 @@ CREATE TABLE posts
     share_count    INTEGER                  DEFAULT 0,
     prevent_download BOOLEAN               DEFAULT FALSE,
     horizontal_type INTEGER                 DEFAULT 1,
+    music_id       BIGINT REFERENCES music (id),
     created_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -248,6 +381,18 @@ CREATE TABLE comments
     )
 );
 
+-- 创建评论提及用户表
+CREATE TABLE comment_mentions
+(
+    id          SERIAL PRIMARY KEY,
+    comment_id  INTEGER REFERENCES comments (id) ON DELETE CASCADE,
+    user_id     VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    start_index INTEGER                  DEFAULT 0,
+    end_index   INTEGER                  DEFAULT 0,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (comment_id, start_index)
+);
+
 -- 创建帖子图片表
 CREATE TABLE post_images
 (
@@ -313,6 +458,7 @@ CREATE TABLE post_text_extra
     extra_type     INTEGER                  DEFAULT 0,
     hashtag_name   VARCHAR(100),
     hashtag_id     VARCHAR(50),
+    user_id        VARCHAR(50),                    -- extra_type 为 0（@用户）时被提及的用户 uid
     is_commerce    BOOLEAN                  DEFAULT FALSE,
     caption_start  INTEGER                  DEFAULT 0,
     caption_end    INTEGER                  DEFAULT 0,
@@ -340,6 +486,30 @@ CREATE TABLE post_video_tags
     created_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 创建草稿表
+CREATE TABLE drafts
+(
+    id             SERIAL PRIMARY KEY,
+    draft_id       VARCHAR(50) UNIQUE NOT NULL,
+    author_user_id VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    draft_type     VARCHAR(20)        NOT NULL,  -- video 视频，post 图文
+    description    TEXT,
+    video_type     VARCHAR(50)              DEFAULT 'recommend-video',
+    media_uri      VARCHAR(255),
+    media_url      TEXT,
+    width          INTEGER                  DEFAULT 0,
+    height         INTEGER                  DEFAULT 0,
+    data_size      BIGINT                   DEFAULT 0,
+    file_hash      VARCHAR(255),
+    duration       INTEGER                  DEFAULT 0,
+    image_urls     TEXT[],                              -- 图文草稿的图片地址
+    music_id       BIGINT REFERENCES music (id) ON DELETE SET NULL,
+    cover_url      TEXT,
+    private_status INTEGER                  DEFAULT 0,
+    created_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 -- 创建商品表
 CREATE TABLE goods
 (
@@ -501,6 +671,145 @@ CREATE TABLE user_friends
     UNIQUE (user_id, friend_id)
 );
 
+-- 创建用户关注关系表
+CREATE TABLE user_follows
+(
+    id           SERIAL PRIMARY KEY,
+    follower_id  VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,  -- 关注者
+    following_id VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,  -- 被关注者
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (follower_id, following_id)
+);
+
+-- 创建用户拉黑表，blocker_id 拉黑了 blocked_id
+CREATE TABLE user_blocks
+(
+    id         SERIAL PRIMARY KEY,
+    blocker_id VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    blocked_id VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (blocker_id, blocked_id)
+);
+
+-- 创建用户隐私设置表，没有记录的用户使用默认设置
+CREATE TABLE user_privacy_settings
+(
+    user_id       VARCHAR(50) PRIMARY KEY REFERENCES users (uid) ON DELETE CASCADE,
+    allow_mention BOOLEAN                  DEFAULT TRUE,  -- 是否允许别人 @ 自己
+    hide_city     BOOLEAN                  DEFAULT FALSE, -- 是否隐藏所在城市（只显示省份）
+    created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建通知表
+CREATE TABLE notifications
+(
+    id          SERIAL PRIMARY KEY,
+    user_id     VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,  -- 接收者
+    sender_id   VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,  -- 触发者
+    notice_type VARCHAR(30)        NOT NULL,                          -- 'new_video' 等
+    aweme_id    VARCHAR(50),
+    content     TEXT,
+    is_read     BOOLEAN                  DEFAULT FALSE,
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建相似视频表，由相似视频任务根据用户共同互动定期计算
+CREATE TABLE video_neighbors
+(
+    video_id     INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    neighbor_id  INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    score        DOUBLE PRECISION NOT NULL,       -- 余弦相似度，0 到 1
+    common_users INTEGER          DEFAULT 0,      -- 共同互动用户数
+    created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (video_id, neighbor_id)
+);
+
+-- 创建视频内容特征表（倒排索引），发布和修改描述时更新，用于按共同话题、音乐、作者和标签查找相似视频
+CREATE TABLE video_content_features
+(
+    video_id INTEGER REFERENCES videos (id) ON DELETE CASCADE,
+    feature  VARCHAR(200)     NOT NULL,  -- 'tag:话题ID'、'music:音乐ID'、'author:uid'、'label:标签'、'sort:分类标签'
+    weight   DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (video_id, feature)
+);
+
+-- 创建热榜快照表，热榜任务每次计算生成一份
+CREATE TABLE trending_snapshots
+(
+    id          SERIAL PRIMARY KEY,
+    computed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建热榜条目表
+CREATE TABLE trending_entries
+(
+    id          SERIAL PRIMARY KEY,
+    snapshot_id INTEGER REFERENCES trending_snapshots (id) ON DELETE CASCADE,
+    kind        VARCHAR(20) NOT NULL,             -- 'video' 或 'hashtag'
+    target_id   INTEGER     NOT NULL,             -- videos.id 或 hashtags.id
+    rank        INTEGER     NOT NULL,
+    score       DOUBLE PRECISION DEFAULT 0,       -- 每小时加权互动数
+    prev_rank   INTEGER,                          -- 上一份快照中的排名，新上榜为 NULL
+    peak_rank   INTEGER     NOT NULL,             -- 快照保留期内的最高排名
+    is_pinned   BOOLEAN          DEFAULT FALSE,   -- 是否由管理员固定
+    UNIQUE (snapshot_id, kind, target_id)
+);
+
+-- 创建热榜人工干预表
+CREATE TABLE trending_overrides
+(
+    kind        VARCHAR(20) NOT NULL,
+    target_id   INTEGER     NOT NULL,
+    action      VARCHAR(10) NOT NULL,             -- 'pin' 固定在 position 位置，'remove' 移出热榜
+    position    INTEGER                  DEFAULT 0,
+    operator_id VARCHAR(50),
+    created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (kind, target_id)
+);
+
+-- 创建 A/B 实验曝光表，每条记录为一次列表请求返回的一个视频及用户所在的分组
+CREATE TABLE experiment_impressions
+(
+    id         SERIAL PRIMARY KEY,
+    experiment VARCHAR(50)  NOT NULL,
+    variant    VARCHAR(50)  NOT NULL,
+    user_id    VARCHAR(50),
+    feed       VARCHAR(20)  NOT NULL,            -- 曝光的列表，如 'recommend'
+    aweme_id   VARCHAR(50)  NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建用户已曝光视频表，两代布隆过滤器：当前一代写满 current_count 达到配置容量后成为上一代
+CREATE TABLE user_seen_filters
+(
+    user_id       VARCHAR(50) PRIMARY KEY,
+    hashes        SMALLINT    NOT NULL,          -- 哈希函数个数，与配置不一致时整体作废
+    current_bits  BYTEA       NOT NULL,
+    current_count INTEGER     NOT NULL DEFAULT 0,
+    previous_bits BYTEA,
+    updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 创建 IP 归属地表，由 paths.ipRegionsFile 配置的 IP 地址库文件导入，用于推断没有填写所在地的用户所在城市
+CREATE TABLE ip_regions
+(
+    id       SERIAL PRIMARY KEY,
+    network  CIDR NOT NULL,
+    country  VARCHAR(100),
+    province VARCHAR(100),
+    city     VARCHAR(100)
+);
+
+-- 创建用户访问信息流记录表，用于标记上次访问之后的新内容
+CREATE TABLE user_feed_visits
+(
+    user_id         VARCHAR(50) REFERENCES users (uid) ON DELETE CASCADE,
+    feed            VARCHAR(30) NOT NULL,                  -- 'following' 等
+    last_visit_time BIGINT      NOT NULL,                  -- 上次获取第一页的时间（Unix 秒）
+    PRIMARY KEY (user_id, feed)
+);
+
 -- 创建用户收藏帖子关系表
 CREATE TABLE user_collect_posts
 (
@@ -521,10 +830,19 @@ CREATE TABLE user_like_posts
     UNIQUE (commenter_id, post_id)
 );
 
+-- 创建数据迁移记录表，sql/migrations 中的脚本执行后在此登记，新建的数据库已包含全部迁移
+CREATE TABLE schema_migrations
+(
+    version    VARCHAR(100) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO schema_migrations (version) VALUES ('001_video_duration_ms');
+
 -- 创建索引
 CREATE INDEX idx_videos_author_user_id ON videos (author_user_id);
 CREATE INDEX idx_videos_type ON videos (video_type);             -- 这里索引名称与实际字段不匹配
 CREATE INDEX idx_videos_aweme_id ON videos (aweme_id);
+CREATE INDEX idx_videos_music_id ON videos (music_id);
 CREATE INDEX idx_comments_video_id ON comments (video_id);
 CREATE INDEX idx_comments_post_id ON comments (post_id);
 CREATE INDEX idx_comments_aweme_id ON comments (aweme_id);
@@ -544,6 +862,10 @@ CREATE INDEX idx_user_history_videos_user_id ON user_history_videos (commenter_i
 CREATE INDEX idx_user_collect_music_user_id ON user_collect_music (commenter_id);
 CREATE INDEX idx_user_collect_posts_user_id ON user_collect_posts (commenter_id);
 CREATE INDEX idx_user_like_posts_user_id ON user_like_posts (commenter_id);
+CREATE INDEX idx_video_content_features_feature ON video_content_features (feature);
+CREATE INDEX idx_trending_entries_target ON trending_entries (kind, target_id, snapshot_id);
+CREATE INDEX idx_experiment_impressions_variant ON experiment_impressions (experiment, variant, created_at);
+CREATE INDEX idx_ip_regions_network ON ip_regions USING gist (network inet_ops);
 
 -- 小红书笔记相关索引
 CREATE INDEX idx_xhs_notes_author_user_id ON xhs_notes (author_user_id);
@@ -557,3 +879,19 @@ CREATE INDEX idx_user_like_xhs_notes_user_id ON user_like_xhs_notes (user_id);
 
 CREATE INDEX idx_user_friends_user_id ON user_friends (user_id);
 CREATE INDEX idx_user_friends_friend_id ON user_friends (friend_id);
+
+CREATE INDEX idx_video_status_deleted ON video_status (is_delete, deleted_at);
+CREATE INDEX idx_videos_author_top ON videos (author_user_id, is_top, top_order);
+CREATE INDEX idx_video_status_scheduled ON video_status (is_scheduled, publish_time);
+CREATE INDEX idx_user_follows_following_id ON user_follows (following_id);
+CREATE INDEX idx_notifications_user_id ON notifications (user_id, created_at);
+CREATE INDEX idx_drafts_author_user_id ON drafts (author_user_id, updated_at);
+CREATE INDEX idx_drafts_updated_at ON drafts (updated_at);
+CREATE INDEX idx_video_hashtags_hashtag_id ON video_hashtags (hashtag_id);
+CREATE INDEX idx_video_mentions_user_id ON video_mentions (user_id);
+CREATE INDEX idx_comment_mentions_user_id ON comment_mentions (user_id);
+CREATE INDEX idx_user_blocks_blocked_id ON user_blocks (blocked_id);
+CREATE INDEX idx_users_unique_id ON users (unique_id);
+CREATE INDEX idx_users_nickname ON users (nickname);
+CREATE INDEX idx_series_author_user_id ON series (author_user_id);
+CREATE INDEX idx_danmaku_video_offset ON danmaku (video_id, offset_ms);